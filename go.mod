@@ -11,6 +11,7 @@ require (
 	github.com/go-chi/render v1.0.1
 	github.com/golang/glog v1.0.0
 	github.com/google/uuid v1.3.0
+	github.com/gorilla/websocket v1.5.0
 	github.com/mitchellh/mapstructure v1.4.3
 	github.com/rs/cors v1.8.2
 	google.golang.org/api v0.65.0
@@ -9,26 +9,46 @@ import (
 	"signmeup/internal/repository"
 )
 
+// Authenticator creates the middlewares that depend on the server's configuration, session verifier and repository.
+type Authenticator struct {
+	cfg      *config.ServerConfig
+	verifier Verifier
+	repo     repository.Repository
+}
+
+func NewAuthenticator(cfg *config.ServerConfig, verifier Verifier, repo repository.Repository) *Authenticator {
+	return &Authenticator{
+		cfg:      cfg,
+		verifier: verifier,
+		repo:     repo,
+	}
+}
+
 // RequireAuth is a middleware that rejects requests without a valid session cookie. The User associated with the
 // request is added to the request context, and can be accessed via GetUserFromRequest.
 
 // AuthCtx is a middleware that extracts the user's session cookie, verifies it, and places the current
 // user into the context used for the rest of the request.
-func AuthCtx() func(http.Handler) http.Handler {
+func (a *Authenticator) AuthCtx() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			tokenCookie, err := r.Cookie(config.Config.SessionCookieName)
+			tokenCookie, err := r.Cookie(a.cfg.SessionCookieName)
 			if err != nil {
 				// Missing session cookie.
 				rejectUnauthorizedRequest(w)
 				return
 			}
 
-			// Verify the session cookie. In this case an additional check is added to detect
-			// if the user's Firebase session was revoked, user deleted/disabled, etc.
-			user, err := repository.Repository.VerifySessionCookie(tokenCookie)
+			// Verify the session cookie.
+			userID, err := a.verifier.VerifySessionCookie(tokenCookie)
+			if err != nil {
+				// Invalid session cookie.
+				rejectUnauthorizedRequest(w)
+				return
+			}
+
+			user, err := a.repo.GetUserByID(userID)
 			if err != nil {
-				// Missing session cookie.
 				rejectUnauthorizedRequest(w)
 				return
 			}
@@ -1,9 +1,12 @@
 package auth
 
 import (
+	"bytes"
+	"encoding/json"
+	"io"
 	"net/http"
 	"signmeup/internal/models"
-	repo "signmeup/internal/repository"
+	"signmeup/internal/qerrors"
 )
 
 func RequireStaffForCourse() func(handler http.Handler) http.Handler {
@@ -46,7 +49,7 @@ func RequireCourseAdmin() func(handler http.Handler) http.Handler {
 	}
 }
 
-func RequireQueueStaff() func(handler http.Handler) http.Handler {
+func (a *Authenticator) RequireQueueStaff() func(handler http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			user, err := GetUserFromRequest(r)
@@ -56,7 +59,7 @@ func RequireQueueStaff() func(handler http.Handler) http.Handler {
 			}
 
 			qID := r.Context().Value("queueID").(string)
-			q, err := repo.Repository.GetQueue(qID)
+			q, err := a.repo.GetQueue(qID)
 			if err != nil {
 				w.WriteHeader(http.StatusInternalServerError)
 				return
@@ -72,16 +75,105 @@ func RequireQueueStaff() func(handler http.Handler) http.Handler {
 	}
 }
 
-func hasCourseStaffPermission(u *models.User, courseID string) bool {
-	if u.IsAdmin {
-		return true
+// RequireQueueVisibility only allows a request to read a queue to continue if the queue's course is visible to the
+// user, which a course restricted to its enrolled users is not to anyone else.
+func (a *Authenticator) RequireQueueVisibility() func(handler http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, err := GetUserFromRequest(r)
+			if err != nil {
+				rejectUnauthorizedRequest(w)
+				return
+			}
+
+			qID := r.Context().Value("queueID").(string)
+			q, err := a.repo.GetQueue(qID)
+			if err != nil {
+				if err == qerrors.QueueNotFoundError {
+					http.Error(w, err.Error(), http.StatusNotFound)
+				} else {
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+				return
+			}
+
+			course, err := a.repo.GetCourseByID(q.CourseID)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			if course.RestrictToEnrolled && !course.IsEnrolled(user) {
+				http.Error(w, qerrors.NotEnrolledError.Error(), http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
 	}
+}
 
-	if _, ok := u.CoursePermissions[courseID]; !ok {
-		return false
+// RequireTicketAccess only allows a request that modifies the ticket whose ID is in its body to continue if the user is
+// a staff member of the queue's course or owns the ticket. Owners may only edit their tickets if editing is set and the
+// queue allows ticket editing, but may always delete them to leave the queue.
+func (a *Authenticator) RequireTicketAccess(editing bool) func(handler http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, err := GetUserFromRequest(r)
+			if err != nil {
+				rejectUnauthorizedRequest(w)
+				return
+			}
+
+			// Read the ticket ID from the body, and restore the body for the handler.
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			var req struct {
+				ID string `json:"id"`
+			}
+			if err := json.Unmarshal(body, &req); err != nil || req.ID == "" {
+				http.Error(w, qerrors.InvalidBody.Error(), http.StatusBadRequest)
+				return
+			}
+
+			qID := r.Context().Value("queueID").(string)
+			q, err := a.repo.GetQueue(qID)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			if hasCourseStaffPermission(user, q.CourseID) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ticket, err := a.repo.GetTicket(qID, req.ID)
+			if err == qerrors.TicketNotFoundError {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			} else if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			if ticket.User.UserID != user.ID || (editing && !q.AllowTicketEditing) {
+				rejectForbiddenRequest(w)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
 	}
+}
 
-	return true
+func hasCourseStaffPermission(u *models.User, courseID string) bool {
+	return u.HasStaffPermission(courseID)
 }
 
 func hasCourseAdminPermission(u *models.User, courseID string) bool {
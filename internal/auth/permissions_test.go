@@ -0,0 +1,250 @@
+package auth_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"signmeup/internal/config"
+	"signmeup/internal/models"
+	"signmeup/internal/repository"
+	"signmeup/internal/server"
+)
+
+// fakeVerifier treats the value of a session cookie as the ID of the user it belongs to.
+type fakeVerifier struct{}
+
+func (fakeVerifier) VerifySessionCookie(sessionCookie *http.Cookie) (string, error) {
+	return sessionCookie.Value, nil
+}
+
+func (fakeVerifier) CreateSessionCookie(idToken string, expiresIn time.Duration) (string, error) {
+	return idToken, nil
+}
+
+// ticketAccessFixture is a server with a queue holding a ticket, and the users that may try to change it.
+type ticketAccessFixture struct {
+	server   *httptest.Server
+	cfg      *config.ServerConfig
+	repo     *repository.MemoryRepository
+	courseID string
+	queueID  string
+	ticketID string
+	users    map[string]*models.User
+}
+
+// newTicketAccessFixture starts a server whose queue allows its tickets to be edited by their owners if
+// allowTicketEditing is set.
+func newTicketAccessFixture(t *testing.T, allowTicketEditing bool) *ticketAccessFixture {
+	t.Helper()
+
+	repo := repository.NewMemoryRepository()
+	users := make(map[string]*models.User)
+	// The first user is a site admin, so it is created before the users under test.
+	for _, name := range []string{"admin", "owner", "student", "staff"} {
+		user, err := repo.Create(&models.CreateUserRequest{
+			Email:       name + "@brown.edu",
+			Password:    "password",
+			DisplayName: name,
+		})
+		if err != nil {
+			t.Fatalf("creating %s: %v", name, err)
+		}
+		users[name] = user
+	}
+
+	course, err := repo.CreateCourse(&models.CreateCourseRequest{Title: "Intro", Code: "cs0150", Term: "fall"})
+	if err != nil {
+		t.Fatalf("creating course: %v", err)
+	}
+	err = repo.AddPermission(&models.AddCoursePermissionRequest{
+		CourseID:   course.ID,
+		Email:      users["staff"].Email,
+		Permission: string(models.CourseStaff),
+	})
+	if err != nil {
+		t.Fatalf("adding staff permission: %v", err)
+	}
+
+	queue, err := repo.CreateQueue(&models.CreateQueueRequest{
+		Title:              "Hours",
+		CourseID:           course.ID,
+		AllowTicketEditing: allowTicketEditing,
+	})
+	if err != nil {
+		t.Fatalf("creating queue: %v", err)
+	}
+	ticket, err := repo.CreateTicket(&models.CreateTicketRequest{
+		QueueID:     queue.ID,
+		CreatedBy:   users["owner"],
+		Description: "help",
+	})
+	if err != nil {
+		t.Fatalf("creating ticket: %v", err)
+	}
+
+	cfg := config.DefaultDevelopmentConfig()
+	ts := httptest.NewServer(server.New(cfg, repo, fakeVerifier{}))
+	t.Cleanup(ts.Close)
+
+	return &ticketAccessFixture{
+		server:   ts,
+		cfg:      cfg,
+		repo:     repo,
+		courseID: course.ID,
+		queueID:  queue.ID,
+		ticketID: ticket.ID,
+		users:    users,
+	}
+}
+
+// do sends a request as the named user and returns the response's status code.
+func (f *ticketAccessFixture) do(t *testing.T, method string, path string, user string, body string) int {
+	t.Helper()
+
+	req, err := http.NewRequest(method, f.server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("creating request: %v", err)
+	}
+	req.AddCookie(&http.Cookie{Name: f.cfg.SessionCookieName, Value: f.users[user].ID})
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("sending request: %v", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestRequireTicketAccess(t *testing.T) {
+	unknownTicketID := "unknown"
+	routes := []struct {
+		name   string
+		method string
+		path   string
+		// body returns the request body for a ticket ID.
+		body func(ticketID string) string
+	}{
+		{
+			name:   "edit",
+			method: http.MethodPatch,
+			path:   "/v1/queues/%s/ticket",
+			body: func(ticketID string) string {
+				return fmt.Sprintf(`{"id":%q,"status":"WAITING","description":"new description"}`, ticketID)
+			},
+		},
+		{
+			name:   "delete",
+			method: http.MethodPost,
+			path:   "/v1/queues/%s/ticket/delete",
+			body: func(ticketID string) string {
+				return fmt.Sprintf(`{"id":%q}`, ticketID)
+			},
+		},
+	}
+
+	tests := []struct {
+		name               string
+		user               string
+		allowTicketEditing bool
+		// ticketID overrides the ID of the queue's ticket in the request body if it is not nil.
+		ticketID *string
+		// want is the status of the request to each route, by the route's name.
+		want map[string]int
+	}{
+		{
+			name:               "owner with editing allowed",
+			user:               "owner",
+			allowTicketEditing: true,
+			want:               map[string]int{"edit": http.StatusOK, "delete": http.StatusOK},
+		},
+		{
+			name: "owner with editing disallowed",
+			user: "owner",
+			// Owners can always leave the queue.
+			want: map[string]int{"edit": http.StatusForbidden, "delete": http.StatusOK},
+		},
+		{
+			name:               "another student",
+			user:               "student",
+			allowTicketEditing: true,
+			want:               map[string]int{"edit": http.StatusForbidden, "delete": http.StatusForbidden},
+		},
+		{
+			name: "course staff",
+			user: "staff",
+			want: map[string]int{"edit": http.StatusOK, "delete": http.StatusOK},
+		},
+		{
+			name:               "missing ticket ID",
+			user:               "owner",
+			allowTicketEditing: true,
+			ticketID:           new(string),
+			want:               map[string]int{"edit": http.StatusBadRequest, "delete": http.StatusBadRequest},
+		},
+		{
+			name:               "unknown ticket ID",
+			user:               "owner",
+			allowTicketEditing: true,
+			ticketID:           &unknownTicketID,
+			want:               map[string]int{"edit": http.StatusNotFound, "delete": http.StatusNotFound},
+		},
+	}
+
+	for _, route := range routes {
+		for _, tt := range tests {
+			t.Run(route.name+"/"+tt.name, func(t *testing.T) {
+				f := newTicketAccessFixture(t, tt.allowTicketEditing)
+
+				ticketID := f.ticketID
+				if tt.ticketID != nil {
+					ticketID = *tt.ticketID
+				}
+				got := f.do(t, route.method, fmt.Sprintf(route.path, f.queueID), tt.user, route.body(ticketID))
+				if want := tt.want[route.name]; got != want {
+					t.Errorf("got status %d, want %d", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestRequireQueueVisibility(t *testing.T) {
+	tests := []struct {
+		name               string
+		user               string
+		restrictToEnrolled bool
+		want               int
+	}{
+		{name: "student of an open course", user: "student", want: http.StatusOK},
+		{name: "student not enrolled in a restricted course", user: "student", restrictToEnrolled: true, want: http.StatusForbidden},
+		{name: "staff of a restricted course", user: "staff", restrictToEnrolled: true, want: http.StatusOK},
+		{name: "site admin of a restricted course", user: "admin", restrictToEnrolled: true, want: http.StatusOK},
+	}
+
+	for _, path := range []string{"/v1/queues/%s/estimate", "/v1/queues/%s/stream"} {
+		for _, tt := range tests {
+			t.Run(path+"/"+tt.name, func(t *testing.T) {
+				f := newTicketAccessFixture(t, false)
+				err := f.repo.EditCourse(&models.EditCourseRequest{
+					CourseID:           f.courseID,
+					Title:              "Intro",
+					Code:               "cs0150",
+					Term:               "fall",
+					RestrictToEnrolled: &tt.restrictToEnrolled,
+					Actor:              f.users["admin"],
+				})
+				if err != nil {
+					t.Fatalf("editing course: %v", err)
+				}
+
+				if got := f.do(t, http.MethodGet, fmt.Sprintf(path, f.queueID), tt.user, ""); got != tt.want {
+					t.Errorf("got status %d, want %d", got, tt.want)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"signmeup/internal/firebase"
+	"time"
+
+	firebaseAuth "firebase.google.com/go/auth"
+)
+
+// Verifier issues and verifies the session cookies used to authenticate requests.
+type Verifier interface {
+	// VerifySessionCookie checks that the given session cookie is valid and returns the ID of the user it belongs to.
+	VerifySessionCookie(sessionCookie *http.Cookie) (string, error)
+	// CreateSessionCookie exchanges an ID token for a session cookie that is valid for expiresIn.
+	CreateSessionCookie(idToken string, expiresIn time.Duration) (string, error)
+}
+
+// FirebaseVerifier is a Verifier backed by Firebase Authentication.
+type FirebaseVerifier struct {
+	authClient *firebaseAuth.Client
+}
+
+func NewFirebaseVerifier(authClient *firebaseAuth.Client) *FirebaseVerifier {
+	return &FirebaseVerifier{authClient: authClient}
+}
+
+func (fv *FirebaseVerifier) VerifySessionCookie(sessionCookie *http.Cookie) (string, error) {
+	// An additional check is added to detect if the user's Firebase session was revoked, user deleted/disabled, etc.
+	decoded, err := fv.authClient.VerifySessionCookieAndCheckRevoked(firebase.Context, sessionCookie.Value)
+	if err != nil {
+		return "", fmt.Errorf("error verifying cookie: %v\n", err)
+	}
+
+	return decoded.UID, nil
+}
+
+func (fv *FirebaseVerifier) CreateSessionCookie(idToken string, expiresIn time.Duration) (string, error) {
+	return fv.authClient.SessionCookie(firebase.Context, idToken, expiresIn)
+}
@@ -7,8 +7,6 @@ import (
 	"time"
 )
 
-var Config *ServerConfig
-
 // ServerConfig is a struct that contains configuration values for the server.
 type ServerConfig struct {
 	// AllowedOrigins is a list of URLs that the server will accept requests from.
@@ -26,6 +24,14 @@ type ServerConfig struct {
 	Port int
 	// FirebaseConfig is the path to the Firebase Admin config JSON.
 	FirebaseConfig string
+	// SchedulerInterval is how often background jobs, such as closing queues past their end time, are run.
+	SchedulerInterval time.Duration
+	// NotifyOnAutoClose should be set to true to notify students still waiting in a queue when it is closed at its
+	// end time.
+	NotifyOnAutoClose bool
+	// ExportHashKey is the secret used to hash the identities of students on anonymized tickets in data exports, so
+	// that their rows can be grouped without revealing who they are.
+	ExportHashKey string
 }
 
 func DefaultDevelopmentConfig() *ServerConfig {
@@ -37,6 +43,9 @@ func DefaultDevelopmentConfig() *ServerConfig {
 		SessionCookieExpiration: time.Hour * 24 * 14,
 		Port:                    8080,
 		FirebaseConfig:          "dev-firebase-config.json",
+		SchedulerInterval:       time.Minute,
+		NotifyOnAutoClose:       true,
+		ExportHashKey:           "development-export-hash-key",
 	}
 }
 
@@ -49,6 +58,9 @@ func DefaultStagingConfig() *ServerConfig {
 		SessionCookieExpiration: time.Hour * 24 * 14,
 		Port:                    8080,
 		FirebaseConfig:          "staging-firebase-config.json",
+		SchedulerInterval:       time.Minute,
+		NotifyOnAutoClose:       true,
+		ExportHashKey:           os.Getenv("EXPORT_HASH_KEY"),
 	}
 }
 
@@ -67,10 +79,34 @@ func DefaultProductionConfig() *ServerConfig {
 		SessionCookieExpiration: time.Hour * 24 * 14,
 		Port:                    port,
 		FirebaseConfig:          "prod-firebase-config.json",
+		SchedulerInterval:       time.Minute,
+		NotifyOnAutoClose:       true,
+		ExportHashKey:           os.Getenv("EXPORT_HASH_KEY"),
 	}
 }
 
-func init() {
-	log.Println("🙂️ No configuration provided. Using the default configuration.")
-	Config = DefaultDevelopmentConfig()
+// FromEnvironment returns the default configuration for the environment named by the HOURS_ENV environment variable,
+// which can be one of "development", "staging" or "production". Staging and production exit if their secrets are not
+// set.
+func FromEnvironment() *ServerConfig {
+	switch os.Getenv("HOURS_ENV") {
+	case "development":
+		return DefaultDevelopmentConfig()
+	case "staging":
+		return requireSecrets(DefaultStagingConfig())
+	case "production":
+		return requireSecrets(DefaultProductionConfig())
+	default:
+		log.Println("🙂️ No configuration provided. Using the default configuration.")
+		return DefaultDevelopmentConfig()
+	}
+}
+
+// requireSecrets exits if a deployed configuration is missing a secret that would otherwise silently default to empty.
+func requireSecrets(cfg *ServerConfig) *ServerConfig {
+	// With an empty key, anyone who knows a student's ID could recompute its hash in an export.
+	if cfg.ExportHashKey == "" {
+		log.Fatalln("EXPORT_HASH_KEY must be set to anonymize data exports")
+	}
+	return cfg
 }
@@ -2,28 +2,16 @@ package firebase
 
 import (
 	"context"
-	"signmeup/internal/config"
 
 	firebaseSDK "firebase.google.com/go"
 	"google.golang.org/api/option"
 )
 
-// App is a global variable to hold the initialized Firebase App object
-var App *firebaseSDK.App
-var Context context.Context
+// Context is the context used for requests made to Firebase.
+var Context = context.Background()
 
-func initializeFirebaseApp() {
-	ctx := context.Background()
-	opt := option.WithCredentialsFile(config.Config.FirebaseConfig)
-	app, err := firebaseSDK.NewApp(ctx, nil, opt)
-	if err != nil {
-		panic(err.Error())
-	}
-
-	App = app
-	Context = ctx
-}
-
-func init() {
-	initializeFirebaseApp()
+// NewApp initializes a Firebase App using the Firebase Admin config JSON at the given path.
+func NewApp(credentialsFile string) (*firebaseSDK.App, error) {
+	opt := option.WithCredentialsFile(credentialsFile)
+	return firebaseSDK.NewApp(Context, nil, opt)
 }
@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// CourseAnalytics summarizes the tickets created in a course's queues over a period.
+type CourseAnalytics struct {
+	CourseID     string               `json:"courseID"`
+	From         time.Time            `json:"from"`
+	To           time.Time            `json:"to"`
+	TotalTickets int                  `json:"totalTickets"`
+	StatusCounts map[TicketStatus]int `json:"statusCounts"`
+	// WaitTime is measured from when a ticket was created to when it was claimed, and ServiceTime from when it was
+	// claimed to when it was completed.
+	WaitTime    DurationStats `json:"waitTime"`
+	ServiceTime DurationStats `json:"serviceTime"`
+	// StaffLoad is the number of tickets claimed by each staff member, busiest first.
+	StaffLoad []StaffLoad `json:"staffLoad"`
+	// BusiestHours is the number of tickets created in each hour of the day, busiest first.
+	BusiestHours []HourCount `json:"busiestHours"`
+	// UniqueStudents is the number of students who created a ticket, and RepeatVisitors is how many of them created
+	// more than one.
+	UniqueStudents int `json:"uniqueStudents"`
+	RepeatVisitors int `json:"repeatVisitors"`
+}
+
+// DurationStats describes a set of durations. The statistics are zero if Count is.
+type DurationStats struct {
+	Count         int   `json:"count"`
+	MedianSeconds int64 `json:"medianSeconds"`
+	P90Seconds    int64 `json:"p90Seconds"`
+}
+
+// StaffLoad is the number of tickets a staff member claimed.
+type StaffLoad struct {
+	UserID  string `json:"userID"`
+	Tickets int    `json:"tickets"`
+}
+
+// HourCount is the number of tickets created in an hour of the day, from 0 to 23.
+type HourCount struct {
+	Hour    int `json:"hour"`
+	Tickets int `json:"tickets"`
+}
+
+// GetCourseAnalyticsRequest is the parameter struct to the GetCourseAnalytics function. Tickets created in [From, To)
+// are counted, and busiest hours are computed in Location.
+type GetCourseAnalyticsRequest struct {
+	CourseID string
+	From     time.Time
+	To       time.Time
+	Location *time.Location
+}
+
+// StudentVisit is one of a student's past tickets in a course, with the queue it was made in and who helped them.
+type StudentVisit struct {
+	TicketID    string       `json:"ticketID"`
+	QueueID     string       `json:"queueID"`
+	QueueTitle  string       `json:"queueTitle"`
+	CreatedAt   time.Time    `json:"createdAt"`
+	ClaimedAt   time.Time    `json:"claimedAt,omitempty"`
+	CompletedAt time.Time    `json:"completedAt,omitempty"`
+	Status      TicketStatus `json:"status"`
+	Description string       `json:"description"`
+	Category    string       `json:"category,omitempty"`
+	// HelpedBy is the ID of the staff member who last claimed the ticket, and HelpedByName is their display name, if
+	// they still have an account.
+	HelpedBy     string `json:"helpedBy,omitempty"`
+	HelpedByName string `json:"helpedByName,omitempty"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+var (
+	FirestoreAuditLogsCollection = "audit_logs"
+)
+
+// AuditAction is a kind of change recorded in the audit log.
+type AuditAction string
+
+const (
+	AuditAddPermission    AuditAction = "ADD_PERMISSION"
+	AuditRemovePermission AuditAction = "REMOVE_PERMISSION"
+	AuditEditCourse       AuditAction = "EDIT_COURSE"
+	AuditDeleteCourse     AuditAction = "DELETE_COURSE"
+	AuditMakeAdmin        AuditAction = "MAKE_ADMIN"
+	AuditBulkUpload       AuditAction = "BULK_UPLOAD"
+	AuditEditInvite       AuditAction = "EDIT_INVITE"
+	AuditRevokeInvite     AuditAction = "REVOKE_INVITE"
+	AuditArchiveCourse    AuditAction = "ARCHIVE_COURSE"
+	AuditUnarchiveCourse  AuditAction = "UNARCHIVE_COURSE"
+	AuditRolloverTerm     AuditAction = "ROLLOVER_TERM"
+)
+
+// AuditLogEntry records a change to courses or access made by a user.
+type AuditLogEntry struct {
+	ID      string      `json:"id" mapstructure:"id"`
+	Action  AuditAction `json:"action" mapstructure:"action"`
+	ActorID string      `json:"actorID" mapstructure:"actorID"`
+	// ActorEmail is kept so that the entry stays readable after the actor's account is deleted.
+	ActorEmail string `json:"actorEmail" mapstructure:"actorEmail"`
+	// CourseID is the course that was changed, or empty for site-wide changes.
+	CourseID string `json:"courseID" mapstructure:"courseID"`
+	// Target is what was changed within the course or site, such as the email or ID of a user.
+	Target string `json:"target" mapstructure:"target"`
+	// Diff maps each changed field to its old and new values.
+	Diff      map[string]AuditChange `json:"diff" mapstructure:"diff"`
+	Timestamp time.Time              `json:"timestamp" mapstructure:"timestamp"`
+}
+
+// AuditChange is the old and new value of a field changed by an audited action. A nil value means that the field was
+// absent.
+type AuditChange struct {
+	Old interface{} `json:"old" mapstructure:"old"`
+	New interface{} `json:"new" mapstructure:"new"`
+}
+
+// GetAuditLogsRequest is the parameter struct to the GetAuditLogs function. Empty filters match every entry.
+type GetAuditLogsRequest struct {
+	CourseID string
+	ActorID  string
+	Action   AuditAction
+	// Cursor is the NextCursor of the previous page, or empty for the first page.
+	Cursor string
+	Limit  int
+}
+
+// AuditLogPage is a page of audit log entries, newest first. NextCursor is empty on the last page.
+type AuditLogPage struct {
+	Entries    []*AuditLogEntry `json:"entries"`
+	NextCursor string           `json:"nextCursor"`
+}
@@ -11,6 +11,8 @@ type CoursePermission string
 const (
 	CourseAdmin CoursePermission = "ADMIN"
 	CourseStaff CoursePermission = "STAFF"
+	// CourseStudent enrolls a user in a course without giving them any staff abilities.
+	CourseStudent CoursePermission = "STUDENT"
 )
 
 type NotificationType string
@@ -45,6 +47,28 @@ type User struct {
 	LastLogInTimestamp int64
 }
 
+// HasStaffPermission returns true if the user is a site admin or is on the staff of the given course.
+func (u *User) HasStaffPermission(courseID string) bool {
+	if u.IsAdmin {
+		return true
+	}
+
+	p, ok := u.CoursePermissions[courseID]
+	return ok && p != CourseStudent
+}
+
+// StaffedCourses returns the IDs of the courses that the user is an admin or staff member of. Unlike
+// HasStaffPermission, being a site admin does not count.
+func (u *User) StaffedCourses() []string {
+	courseIDs := make([]string, 0, len(u.CoursePermissions))
+	for courseID, p := range u.CoursePermissions {
+		if p != CourseStudent {
+			courseIDs = append(courseIDs, courseID)
+		}
+	}
+	return courseIDs
+}
+
 type Notification struct {
 	ID        string           `json:"id" mapstructure:"id"`
 	Title     string           `json:"title" mapstructure:"title"`
@@ -73,6 +97,7 @@ type UpdateUserRequest struct {
 type MakeAdminByEmailRequest struct {
 	Email   string `json:"email"`
 	IsAdmin bool   `json:"isAdmin"`
+	Actor   *User  `json:"-"`
 }
 
 // ClearNotificationRequest is the parameter struct for the ClearNotification function.
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 var (
 	FirestoreCoursesCollection = "courses"
 	FirestoreInvitesCollection = "invites"
@@ -12,12 +14,70 @@ type Course struct {
 	Term              string                      `json:"term" mapstructure:"term"`
 	IsArchived        bool                        `json:"isArchived" mapstructure:"isArchived"`
 	CoursePermissions map[string]CoursePermission `json:"coursePermissions" mapstructure:"coursePermissions"`
+	// RestrictToEnrolled limits reading the course, and reading and joining its queues, to users with a role in it.
+	RestrictToEnrolled bool `json:"restrictToEnrolled" mapstructure:"restrictToEnrolled"`
+}
+
+// IsEnrolled returns true if the user is a site admin or has any role in the course, including as a student.
+func (c *Course) IsEnrolled(user *User) bool {
+	if user.IsAdmin {
+		return true
+	}
+
+	_, ok := c.CoursePermissions[user.ID]
+	return ok
 }
 
+// CourseInvite grants a course permission to the user with Email when they first log in.
 type CourseInvite struct {
-	Email      string `json:"email"`
-	CourseID   string `json:"courseID"`
-	Permission string `json:"permission"`
+	ID         string    `json:"id" mapstructure:"id"`
+	Email      string    `json:"email" mapstructure:"email"`
+	CourseID   string    `json:"courseID" mapstructure:"courseID"`
+	Permission string    `json:"permission" mapstructure:"permission"`
+	InvitedBy  string    `json:"invitedBy" mapstructure:"invitedBy"`
+	CreatedAt  time.Time `json:"createdAt" mapstructure:"createdAt"`
+	// ExpiresAt is when the invite can no longer be redeemed, or zero if it never expires.
+	ExpiresAt time.Time `json:"expiresAt" mapstructure:"expiresAt"`
+}
+
+// Expired returns true if the invite can no longer be redeemed at now.
+func (i *CourseInvite) Expired(now time.Time) bool {
+	return !i.ExpiresAt.IsZero() && !now.Before(i.ExpiresAt)
+}
+
+// CourseSort is a field that course listings can be sorted by.
+type CourseSort string
+
+const (
+	CourseSortCode  CourseSort = "code"
+	CourseSortTitle CourseSort = "title"
+	CourseSortTerm  CourseSort = "term"
+)
+
+// ListCoursesRequest is the parameter struct to the ListCourses function. Empty filters match every course.
+type ListCoursesRequest struct {
+	Term string
+	// CodePrefix matches the start of course codes, ignoring case.
+	CodePrefix string
+	// Archived selects archived or unarchived courses, or both if nil.
+	Archived *bool
+	// CourseIDs limits the listing to the given courses if it is not nil.
+	CourseIDs []string
+	// Viewer is the user the listing is for. Courses restricted to enrolled users are hidden from those not enrolled.
+	Viewer *User
+	// Sort defaults to CourseSortCode. Ties are broken by code and then by ID.
+	Sort       CourseSort
+	Descending bool
+	// Cursor is the NextCursor of the previous page, or empty for the first page. The listing resumes after the position
+	// of the previous page's last course, even if that course has since changed or no longer matches.
+	Cursor string
+	Limit  int
+}
+
+// CoursePage is a page of a course listing. NextCursor is empty on the last page.
+type CoursePage struct {
+	Courses    []*Course `json:"courses"`
+	NextCursor string    `json:"nextCursor"`
 }
 
 type GetCourseRequest struct {
@@ -25,14 +85,16 @@ type GetCourseRequest struct {
 }
 
 type CreateCourseRequest struct {
-	Title     string `json:"title"`
-	Code      string `json:"code"`
-	Term      string `json:"term"`
-	CreatedBy *User  `json:"omitempty"`
+	Title              string `json:"title"`
+	Code               string `json:"code"`
+	Term               string `json:"term"`
+	RestrictToEnrolled bool   `json:"restrictToEnrolled"`
+	CreatedBy          *User  `json:"omitempty"`
 }
 
 type DeleteCourseRequest struct {
 	CourseID string `json:"courseID"`
+	Actor    *User  `json:"-"`
 }
 
 type EditCourseRequest struct {
@@ -40,21 +102,153 @@ type EditCourseRequest struct {
 	Title    string `json:"title"`
 	Code     string `json:"code"`
 	Term     string `json:"term"`
+	// RestrictToEnrolled is left unchanged if it is nil, so that clients that do not know about it cannot clear it.
+	RestrictToEnrolled *bool `json:"restrictToEnrolled"`
+	Actor              *User `json:"-"`
 }
 
 type AddCoursePermissionRequest struct {
 	CourseID   string `json:"courseID"`
 	Email      string `json:"email"`
 	Permission string `json:"permission"`
+	// ExpiresAt is when the invite expires, if the email does not belong to a user yet. Zero means never.
+	ExpiresAt time.Time `json:"expiresAt"`
+	Actor     *User     `json:"-"`
 }
 
 type RemoveCoursePermissionRequest struct {
 	CourseID string `json:"courseID"`
 	UserID   string `json:"userID"`
+	Actor    *User  `json:"-"`
+}
+
+// SetCourseArchivedRequest is the parameter struct to the SetCourseArchived function.
+type SetCourseArchivedRequest struct {
+	CourseID string `json:"courseID"`
+	Archived bool   `json:"archived"`
+	Actor    *User  `json:"-"`
+}
+
+// ArchiveCoursesByTermRequest is the parameter struct to the ArchiveCoursesByTerm function.
+type ArchiveCoursesByTermRequest struct {
+	Term  string `json:"term"`
+	Actor *User  `json:"-"`
 }
 
+// RolloverTermRequest is the parameter struct to the RolloverTerm function. Each course of FromTerm is cloned into
+// ToTerm with its settings and queue templates, but not its schedules, and with its admins and staff if CopyStaff is
+// set. The courses that were cloned are then archived.
+type RolloverTermRequest struct {
+	FromTerm  string `json:"fromTerm"`
+	ToTerm    string `json:"toTerm"`
+	CopyStaff bool   `json:"copyStaff"`
+	Actor     *User  `json:"-"`
+}
+
+// RolloverOutcome is what a term rollover did with a course.
+type RolloverOutcome string
+
+const (
+	// RolloverCloned courses were cloned into the new term and archived.
+	RolloverCloned RolloverOutcome = "CLONED"
+	// RolloverConflict courses were left alone, because a course with their code already exists in the new term.
+	RolloverConflict RolloverOutcome = "CONFLICT"
+	// RolloverFailed courses could not be fully cloned, for the reason given in the course's report. They are not
+	// archived, and their partial clones are deleted.
+	RolloverFailed RolloverOutcome = "FAILED"
+)
+
+// RolloverReport describes what a term rollover did with each course of the old term.
+type RolloverReport struct {
+	FromTerm string                  `json:"fromTerm"`
+	ToTerm   string                  `json:"toTerm"`
+	Courses  []*RolloverCourseReport `json:"courses"`
+}
+
+// RolloverCourseReport describes what a term rollover did with a course. NewCourseID is the ID of the clone, of the
+// conflicting course, or of a partial clone that could not be deleted. Templates and Staff count the queue templates and permissions that were copied.
+type RolloverCourseReport struct {
+	Code        string          `json:"code"`
+	OldCourseID string          `json:"oldCourseID"`
+	NewCourseID string          `json:"newCourseID,omitempty"`
+	Outcome     RolloverOutcome `json:"outcome"`
+	Templates   int             `json:"templates"`
+	Staff       int             `json:"staff"`
+	Reason      string          `json:"reason,omitempty"`
+}
+
+// BulkUploadRequest is the parameter struct to the BulkUpload function. Data is a CSV file with a header row naming
+// the email, role, course_code and course_name columns, in any order. Roles are ADMIN, STAFF or STUDENT, or HTA and
+// UTA for ADMIN and STAFF. If DryRun is set, the report describes what the upload would do without changing anything.
 type BulkUploadRequest struct {
 	Term      string `json:"term" mapstructure:"term"`
 	Data      string `json:"data" mapstructure:"data"`
+	DryRun    bool   `json:"dryRun" mapstructure:"dryRun"`
 	CreatedBy *User  `json:"omitempty"`
 }
+
+// BulkUploadOutcome is what a bulk upload did with a row.
+type BulkUploadOutcome string
+
+const (
+	// BulkUploadGranted rows gave an existing user their role.
+	BulkUploadGranted BulkUploadOutcome = "GRANTED"
+	// BulkUploadInvited rows invited an email without an account, as AddPermission does.
+	BulkUploadInvited BulkUploadOutcome = "INVITED"
+	// BulkUploadUnchanged rows described a role or invite that already existed.
+	BulkUploadUnchanged BulkUploadOutcome = "UNCHANGED"
+	// BulkUploadRejected rows were not applied, for the reason given in the row's report.
+	BulkUploadRejected BulkUploadOutcome = "REJECTED"
+)
+
+// BulkUploadReport describes what a bulk upload did, or would do in a dry run. CreatedCourses lists the codes of the
+// courses it created.
+type BulkUploadReport struct {
+	DryRun         bool                   `json:"dryRun"`
+	CreatedCourses []string               `json:"createdCourses"`
+	Rows           []*BulkUploadRowReport `json:"rows"`
+}
+
+// BulkUploadRowReport describes what a bulk upload did with one row of its data. Row is the row's position in the
+// data, counting the header as row 1.
+type BulkUploadRowReport struct {
+	Row        int               `json:"row"`
+	Email      string            `json:"email"`
+	CourseCode string            `json:"courseCode"`
+	Permission string            `json:"permission"`
+	Outcome    BulkUploadOutcome `json:"outcome"`
+	Reason     string            `json:"reason,omitempty"`
+}
+
+// ImportRosterRequest is the parameter struct to the ImportRoster function.
+type ImportRosterRequest struct {
+	CourseID string   `json:"courseID"`
+	Emails   []string `json:"emails"`
+	Actor    *User    `json:"-"`
+}
+
+// RosterImportResult reports what happened to each email of an imported roster. Enrolled users already had accounts,
+// invited ones are enrolled when they first log in, and skipped ones already had a role in the course, which was kept.
+type RosterImportResult struct {
+	Enrolled []string `json:"enrolled"`
+	Invited  []string `json:"invited"`
+	Skipped  []string `json:"skipped"`
+}
+
+// EditInviteRequest is the parameter struct to the EditInvite function.
+type EditInviteRequest struct {
+	CourseID   string `json:"courseID"`
+	InviteID   string `json:"inviteID"`
+	Permission string `json:"permission"`
+	// ExpiresAt is left unchanged if it is nil, so that changing only the permission does not clear it. A zero time
+	// makes the invite never expire.
+	ExpiresAt *time.Time `json:"expiresAt"`
+	Actor     *User      `json:"-"`
+}
+
+// RevokeInviteRequest is the parameter struct to the RevokeInvite function.
+type RevokeInviteRequest struct {
+	CourseID string `json:"courseID"`
+	InviteID string `json:"inviteID"`
+	Actor    *User  `json:"-"`
+}
@@ -0,0 +1,32 @@
+package models
+
+// ExportFormat is a file format that queue and ticket data can be exported in.
+type ExportFormat string
+
+const (
+	ExportCSV    ExportFormat = "csv"
+	ExportNDJSON ExportFormat = "ndjson"
+)
+
+// TicketExportRow is a row of a data export, describing a ticket and the queue it was made in. Times are formatted with
+// RFC 3339, and are empty if the ticket has not reached that point. On anonymized tickets, UserID is a hash of the
+// student's ID and the other identity fields are empty.
+type TicketExportRow struct {
+	CourseID      string       `json:"courseID"`
+	QueueID       string       `json:"queueID"`
+	QueueTitle    string       `json:"queueTitle"`
+	QueueLocation string       `json:"queueLocation"`
+	TicketID      string       `json:"ticketID"`
+	Status        TicketStatus `json:"status"`
+	Category      string       `json:"category"`
+	Description   string       `json:"description"`
+	CreatedAt     string       `json:"createdAt"`
+	ClaimedAt     string       `json:"claimedAt"`
+	ClaimedBy     string       `json:"claimedBy"`
+	CompletedAt   string       `json:"completedAt"`
+	Anonymized    bool         `json:"anonymized"`
+	UserID        string       `json:"userID"`
+	Email         string       `json:"email"`
+	DisplayName   string       `json:"displayName"`
+	Pronouns      string       `json:"pronouns"`
+}
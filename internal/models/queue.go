@@ -1,10 +1,15 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 var (
-	FirestoreQueuesCollection  = "queues"
-	FirestoreTicketsCollection = "tickets"
+	FirestoreQueuesCollection       = "queues"
+	FirestoreTicketsCollection      = "tickets"
+	FirestoreShufflesCollection     = "shuffles"
+	FirestoreTicketEventsCollection = "events"
 )
 
 // MaskPolicy is an integer between 0 and 3 that determines the face mask policy for a queue.
@@ -13,21 +18,37 @@ var (
 // 2: Face masks required
 type MaskPolicy int
 
+// OrderingPolicy determines where a new ticket is placed among a queue's pending tickets.
+type OrderingPolicy string
+
+const (
+	// OrderFIFO places new tickets at the end of the queue. Queues without a policy are FIFO.
+	OrderFIFO OrderingPolicy = "FIFO"
+	// OrderFirstTimersFirst places tickets from students who have not been helped in the queue in the past day ahead
+	// of tickets from students who have.
+	OrderFirstTimersFirst OrderingPolicy = "FIRST_TIMERS_FIRST"
+	// OrderFewestTickets places tickets from students who have made fewer tickets in the queue in the past week ahead
+	// of tickets from students who have made more, which serves students round-robin.
+	OrderFewestTickets OrderingPolicy = "FEWEST_TICKETS"
+)
+
 type Queue struct {
-	ID                 string     `json:"id" mapstructure:"id"`
-	Title              string     `json:"title" mapstructure:"title"`
-	Description        string     `json:"code" mapstructure:"code"`
-	Location           string     `json:"location" mapstructure:"location"`
-	EndTime            time.Time  `json:"endTime" mapstructure:"endTime"`
-	ShowMeetingLinks   bool       `json:"showMeetingLinks" mapstructure:"showMeetingLinks"`
-	AllowTicketEditing bool       `json:"allowTicketEditing" mapstructure:"allowTicketEditing"`
-	CourseID           string     `json:"courseID" mapstructure:"courseID"`
-	Course             *Course    `json:"course" mapstructure:"course,omitempty"`
-	IsCutOff           bool       `json:"isCutOff" mapstructure:"isCutOff,omitempty"`
-	PendingTickets     []string   `json:"pendingTickets" mapstructure:"pendingTickets"`
-	CompletedTickets   []string   `json:"completedTickets" mapstructure:"completedTickets"`
-	FaceMaskPolicy     MaskPolicy `json:"faceMaskPolicy" mapstructure:"faceMaskPolicy"`
-	RejoinCooldown     int        `json:"rejoinCooldown" mapstructure:"rejoinCooldown"`
+	ID                 string         `json:"id" mapstructure:"id"`
+	Title              string         `json:"title" mapstructure:"title"`
+	Description        string         `json:"code" mapstructure:"code"`
+	Location           string         `json:"location" mapstructure:"location"`
+	EndTime            time.Time      `json:"endTime" mapstructure:"endTime"`
+	ShowMeetingLinks   bool           `json:"showMeetingLinks" mapstructure:"showMeetingLinks"`
+	AllowTicketEditing bool           `json:"allowTicketEditing" mapstructure:"allowTicketEditing"`
+	CourseID           string         `json:"courseID" mapstructure:"courseID"`
+	Course             *Course        `json:"course" mapstructure:"course,omitempty"`
+	IsCutOff           bool           `json:"isCutOff" mapstructure:"isCutOff,omitempty"`
+	PendingTickets     []string       `json:"pendingTickets" mapstructure:"pendingTickets"`
+	CompletedTickets   []string       `json:"completedTickets" mapstructure:"completedTickets"`
+	FaceMaskPolicy     MaskPolicy     `json:"faceMaskPolicy" mapstructure:"faceMaskPolicy"`
+	RejoinCooldown     int            `json:"rejoinCooldown" mapstructure:"rejoinCooldown"`
+	OrderingPolicy     OrderingPolicy `json:"orderingPolicy" mapstructure:"orderingPolicy"`
+	Categories         []string       `json:"categories" mapstructure:"categories"`
 }
 
 type TicketStatus string
@@ -59,33 +80,132 @@ type Ticket struct {
 	Status      TicketStatus   `json:"status" mapstructure:"status"`
 	Description string         `json:"description"`
 	Anonymize   bool           `json:"anonymize"`
+	Category    string         `json:"category,omitempty" mapstructure:"category"`
+}
+
+type TicketEventType string
+
+const (
+	TicketCreated TicketEventType = "CREATED"
+	TicketEdited  TicketEventType = "EDITED"
+	TicketDeleted TicketEventType = "DELETED"
+)
+
+// TicketEvent is an entry in a ticket's append-only history. From is empty for created tickets, and To is empty for
+// deleted tickets. Descriptions are only set when the description changed.
+type TicketEvent struct {
+	ID             string          `json:"id" mapstructure:"id"`
+	TicketID       string          `json:"ticketID" mapstructure:"ticketID"`
+	Type           TicketEventType `json:"type" mapstructure:"type"`
+	ActorID        string          `json:"actorID" mapstructure:"actorID"`
+	Timestamp      time.Time       `json:"timestamp" mapstructure:"timestamp"`
+	From           TicketStatus    `json:"from,omitempty" mapstructure:"from"`
+	To             TicketStatus    `json:"to,omitempty" mapstructure:"to"`
+	OldDescription string          `json:"oldDescription,omitempty" mapstructure:"oldDescription"`
+	NewDescription string          `json:"newDescription,omitempty" mapstructure:"newDescription"`
+}
+
+// QueueSnapshot is the state of a queue and all of its tickets at a point in time.
+type QueueSnapshot struct {
+	Queue *Queue
+	// Map from ticket ID to Ticket.
+	Tickets map[string]*Ticket
+}
+
+// TicketStatusChange describes a ticket moving from one status to another. From is empty for new tickets.
+type TicketStatusChange struct {
+	TicketID string       `json:"ticketID"`
+	From     TicketStatus `json:"from,omitempty"`
+	To       TicketStatus `json:"to"`
+}
+
+type TicketCommandType string
+
+const (
+	CommandClaim    TicketCommandType = "claim"
+	CommandReturn   TicketCommandType = "return"
+	CommandMissing  TicketCommandType = "missing"
+	CommandComplete TicketCommandType = "complete"
+)
+
+// TicketCommand is sent by staff over a queue's WebSocket to change the status of a ticket.
+type TicketCommand struct {
+	// ID is chosen by the client and echoed back in the command's acknowledgement or error.
+	ID       string            `json:"id"`
+	Command  TicketCommandType `json:"command"`
+	TicketID string            `json:"ticketID"`
+}
+
+// QueueSocketMessage is sent to clients over a queue's WebSocket. Type is "queue", "tickets" or "ticket" for queue
+// updates, and "ack" or "error" in response to a TicketCommand.
+type QueueSocketMessage struct {
+	Type  string          `json:"type"`
+	ID    string          `json:"id,omitempty"`
+	Data  json.RawMessage `json:"data,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
+
+// WaitEstimate estimates how long each of a queue's pending tickets will wait before being claimed.
+type WaitEstimate struct {
+	QueueID string `json:"queueID"`
+	// AverageServiceSeconds is the mean time from a ticket being claimed to being completed, over the queue's most
+	// recently completed tickets.
+	AverageServiceSeconds int64 `json:"averageServiceSeconds"`
+	// SampleSize is the number of tickets AverageServiceSeconds was computed from. If it is zero, a default is used.
+	SampleSize int `json:"sampleSize"`
+	// ActiveStaff is the number of staff members who have recently claimed a ticket.
+	ActiveStaff int `json:"activeStaff"`
+	// EstimatedWaitSeconds is the estimated wait for a ticket created now.
+	EstimatedWaitSeconds int64                `json:"estimatedWaitSeconds"`
+	Tickets              []TicketWaitEstimate `json:"tickets"`
+}
+
+// TicketWaitEstimate is the estimated wait of a single pending ticket. Position is 1 for the next ticket to be claimed,
+// and 0 for tickets that have already been claimed.
+type TicketWaitEstimate struct {
+	TicketID             string `json:"ticketID"`
+	Position             int    `json:"position"`
+	EstimatedWaitSeconds int64  `json:"estimatedWaitSeconds"`
+}
+
+// CreateTicketResponse is returned when a ticket is created, along with the ticket's estimated wait.
+type CreateTicketResponse struct {
+	*Ticket
+	EstimatedWaitSeconds int64 `json:"estimatedWaitSeconds"`
 }
 
 // CreateQueueRequest is the parameter struct to the CreateQueue function.
 type CreateQueueRequest struct {
-	Title              string     `json:"title"`
-	Description        string     `json:"description"`
-	Location           string     `json:"location"`
-	ShowMeetingLinks   bool       `json:"showMeetingLinks" mapstructure:"showMeetingLinks"`
-	AllowTicketEditing bool       `json:"allowTicketEditing" mapstructure:"allowTicketEditing"`
-	EndTime            time.Time  `json:"endTime"`
-	CourseID           string     `json:"courseID"`
-	FaceMaskPolicy     MaskPolicy `json:"faceMaskPolicy" mapstructure:"faceMaskPolicy"`
-	RejoinCooldown     int        `json:"rejoinCooldown" mapstructure:"rejoinCooldown"`
+	Title              string         `json:"title"`
+	Description        string         `json:"description"`
+	Location           string         `json:"location"`
+	ShowMeetingLinks   bool           `json:"showMeetingLinks" mapstructure:"showMeetingLinks"`
+	AllowTicketEditing bool           `json:"allowTicketEditing" mapstructure:"allowTicketEditing"`
+	EndTime            time.Time      `json:"endTime"`
+	CourseID           string         `json:"courseID"`
+	FaceMaskPolicy     MaskPolicy     `json:"faceMaskPolicy" mapstructure:"faceMaskPolicy"`
+	RejoinCooldown     int            `json:"rejoinCooldown" mapstructure:"rejoinCooldown"`
+	OrderingPolicy     OrderingPolicy `json:"orderingPolicy" mapstructure:"orderingPolicy"`
+	Categories         []string       `json:"categories" mapstructure:"categories"`
+	// TemplateID, if set, is the ID of a QueueTemplate of the course whose settings are used for any field not given
+	// in the request.
+	TemplateID string `json:"templateID"`
 }
 
 // EditQueueRequest is the parameter struct to the EditQueue function.
 type EditQueueRequest struct {
-	QueueID            string     `json:"queueID,omitempty"`
-	Title              string     `json:"title"`
-	Description        string     `json:"description"`
-	Location           string     `json:"location"`
-	ShowMeetingLinks   bool       `json:"showMeetingLinks" mapstructure:"showMeetingLinks"`
-	AllowTicketEditing bool       `json:"allowTicketEditing" mapstructure:"allowTicketEditing"`
-	EndTime            time.Time  `json:"endTime"`
-	IsCutOff           bool       `json:"isCutOff"`
-	FaceMaskPolicy     MaskPolicy `json:"faceMaskPolicy" mapstructure:"faceMaskPolicy"`
-	RejoinCooldown     int        `json:"rejoinCooldown" mapstructure:"rejoinCooldown"`
+	QueueID            string         `json:"queueID,omitempty"`
+	Title              string         `json:"title"`
+	Description        string         `json:"description"`
+	Location           string         `json:"location"`
+	ShowMeetingLinks   bool           `json:"showMeetingLinks" mapstructure:"showMeetingLinks"`
+	AllowTicketEditing bool           `json:"allowTicketEditing" mapstructure:"allowTicketEditing"`
+	EndTime            time.Time      `json:"endTime"`
+	IsCutOff           bool           `json:"isCutOff"`
+	FaceMaskPolicy     MaskPolicy     `json:"faceMaskPolicy" mapstructure:"faceMaskPolicy"`
+	RejoinCooldown     int            `json:"rejoinCooldown" mapstructure:"rejoinCooldown"`
+	OrderingPolicy     OrderingPolicy `json:"orderingPolicy" mapstructure:"orderingPolicy"`
+	Categories         []string       `json:"categories" mapstructure:"categories"`
 }
 
 // DeleteQueueRequest is the parameter struct to the CreateQueue function.
@@ -97,10 +217,26 @@ type DeleteQueueRequest struct {
 type CutoffQueueRequest struct {
 	IsCutOff bool   `json:"isCutOff"`
 	QueueID  string `json:",omitempty"`
+	// EndedBy, if set, only cuts off the queue if it is still open and its EndTime is before EndedBy. Otherwise,
+	// qerrors.QueueNotEndedError is returned.
+	EndedBy time.Time `json:"-"`
 }
 
 type ShuffleQueueRequest struct {
-	QueueID string `json:"queueID,omitempty"`
+	QueueID    string `json:"queueID,omitempty"`
+	ShuffledBy *User  `json:"shuffledBy,omitempty"`
+}
+
+// ShuffleRecord is an audit entry describing a single shuffle of a queue. Shuffling Before using a math/rand source
+// seeded with Seed reproduces After, which shows that the new order was not chosen by hand.
+type ShuffleRecord struct {
+	ID         string    `json:"id" mapstructure:"id"`
+	QueueID    string    `json:"queueID" mapstructure:"queueID"`
+	Seed       int64     `json:"seed" mapstructure:"seed"`
+	Before     []string  `json:"before" mapstructure:"before"`
+	After      []string  `json:"after" mapstructure:"after"`
+	ShuffledBy string    `json:"shuffledBy" mapstructure:"shuffledBy"`
+	ShuffledAt time.Time `json:"shuffledAt" mapstructure:"shuffledAt"`
 }
 
 // CreateTicketRequest is the parameter struct to the CreateTicket function.
@@ -109,22 +245,35 @@ type CreateTicketRequest struct {
 	CreatedBy   *User  `json:"createdBy,omitempty"`
 	Description string `json:"description"`
 	Anonymize   bool   `json:"anonymize"`
+	// Category must be one of the queue's categories, if it has any.
+	Category string `json:"category"`
+}
+
+// ClaimNextTicketRequest is the parameter struct to the ClaimNextTicket function.
+type ClaimNextTicketRequest struct {
+	QueueID   string `json:"queueID,omitempty"`
+	ClaimedBy *User  `json:"claimedBy,omitempty"`
+	// Category, if set, limits the claim to tickets in that category.
+	Category string `json:"category"`
 }
 
 // EditTicketRequest is the parameter struct to the EditTicket function.
 type EditTicketRequest struct {
 	ID          string       `json:"id" mapstructure:"id"`
 	QueueID     string       `json:"queueID,omitempty"`
-	OwnerID     string       `json:"ownerID" mapstructure:"ownerID"`
 	Status      TicketStatus `json:"status" mapstructure:"status"`
 	Description string       `json:"description"`
-	ClaimedBy   *User        `json:"claimedBy,omitempty"`
+	// KeepDescription leaves the ticket's description as it is when the edit is made, so that a change of status
+	// cannot undo a concurrent edit of the description.
+	KeepDescription bool  `json:"-"`
+	ClaimedBy       *User `json:"claimedBy,omitempty"`
 }
 
 // DeleteTicketRequest is the parameter struct to the DeleteTicket function.
 type DeleteTicketRequest struct {
-	ID      string `json:"id" mapstructure:"id"`
-	QueueID string `json:"queueID,omitempty"`
+	ID        string `json:"id" mapstructure:"id"`
+	QueueID   string `json:"queueID,omitempty"`
+	DeletedBy *User  `json:"deletedBy,omitempty"`
 }
 
 // MakeAnnouncementRequest is the parameter struct to the MakeAnnouncement function.
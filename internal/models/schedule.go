@@ -0,0 +1,114 @@
+package models
+
+import "time"
+
+var (
+	FirestoreSchedulesCollection = "schedules"
+)
+
+const (
+	// ScheduleTimeLayout is the layout of a Schedule's start and end times of day.
+	ScheduleTimeLayout = "15:04"
+	// ScheduleDateLayout is the layout of a Schedule's exception dates and occurrence dates.
+	ScheduleDateLayout = "2006-01-02"
+)
+
+// Schedule is a recurring weekly office hours session of a course. A queue is created for each session when it starts,
+// and closed when it ends.
+type Schedule struct {
+	ID          string       `json:"id" mapstructure:"id"`
+	CourseID    string       `json:"courseID" mapstructure:"courseID"`
+	Title       string       `json:"title" mapstructure:"title"`
+	Description string       `json:"description" mapstructure:"description"`
+	Location    string       `json:"location" mapstructure:"location"`
+	Weekday     time.Weekday `json:"weekday" mapstructure:"weekday"`
+	// StartTime and EndTime are times of day in TimeZone, formatted with ScheduleTimeLayout.
+	StartTime string `json:"startTime" mapstructure:"startTime"`
+	EndTime   string `json:"endTime" mapstructure:"endTime"`
+	// TimeZone is an IANA time zone name, such as "America/New_York".
+	TimeZone       string     `json:"timeZone" mapstructure:"timeZone"`
+	FaceMaskPolicy MaskPolicy `json:"faceMaskPolicy" mapstructure:"faceMaskPolicy"`
+	RejoinCooldown int        `json:"rejoinCooldown" mapstructure:"rejoinCooldown"`
+	// Staff are the IDs of the staff members assigned to the session, who are notified when its queue opens.
+	Staff []string `json:"staff" mapstructure:"staff"`
+	// ExceptionDates are dates, formatted with ScheduleDateLayout, on which the session is not held, such as holidays.
+	ExceptionDates []string `json:"exceptionDates" mapstructure:"exceptionDates"`
+	// LastOccurrence is the date of the most recent session that a queue was created for, and LastQueueID is that
+	// queue's ID.
+	LastOccurrence string `json:"lastOccurrence" mapstructure:"lastOccurrence"`
+	LastQueueID    string `json:"lastQueueID" mapstructure:"lastQueueID"`
+}
+
+// Occurrence returns the date, start and end of the session in progress at now. ok is false if no session is in
+// progress, including on exception dates.
+func (s *Schedule) Occurrence(now time.Time) (date string, start time.Time, end time.Time, ok bool) {
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return "", time.Time{}, time.Time{}, false
+	}
+
+	local := now.In(loc)
+	if local.Weekday() != s.Weekday {
+		return "", time.Time{}, time.Time{}, false
+	}
+
+	date = local.Format(ScheduleDateLayout)
+	for _, exception := range s.ExceptionDates {
+		if exception == date {
+			return "", time.Time{}, time.Time{}, false
+		}
+	}
+
+	start, err = time.ParseInLocation(ScheduleDateLayout+" "+ScheduleTimeLayout, date+" "+s.StartTime, loc)
+	if err != nil {
+		return "", time.Time{}, time.Time{}, false
+	}
+	end, err = time.ParseInLocation(ScheduleDateLayout+" "+ScheduleTimeLayout, date+" "+s.EndTime, loc)
+	if err != nil {
+		return "", time.Time{}, time.Time{}, false
+	}
+
+	if local.Before(start) || !local.Before(end) {
+		return "", time.Time{}, time.Time{}, false
+	}
+	return date, start, end, true
+}
+
+// CreateScheduleRequest is the parameter struct to the CreateSchedule function.
+type CreateScheduleRequest struct {
+	CourseID       string       `json:"courseID"`
+	Title          string       `json:"title"`
+	Description    string       `json:"description"`
+	Location       string       `json:"location"`
+	Weekday        time.Weekday `json:"weekday"`
+	StartTime      string       `json:"startTime"`
+	EndTime        string       `json:"endTime"`
+	TimeZone       string       `json:"timeZone"`
+	FaceMaskPolicy MaskPolicy   `json:"faceMaskPolicy"`
+	RejoinCooldown int          `json:"rejoinCooldown"`
+	Staff          []string     `json:"staff"`
+	ExceptionDates []string     `json:"exceptionDates"`
+}
+
+// EditScheduleRequest is the parameter struct to the EditSchedule function.
+type EditScheduleRequest struct {
+	CourseID       string       `json:"courseID"`
+	ScheduleID     string       `json:"scheduleID"`
+	Title          string       `json:"title"`
+	Description    string       `json:"description"`
+	Location       string       `json:"location"`
+	Weekday        time.Weekday `json:"weekday"`
+	StartTime      string       `json:"startTime"`
+	EndTime        string       `json:"endTime"`
+	TimeZone       string       `json:"timeZone"`
+	FaceMaskPolicy MaskPolicy   `json:"faceMaskPolicy"`
+	RejoinCooldown int          `json:"rejoinCooldown"`
+	Staff          []string     `json:"staff"`
+	ExceptionDates []string     `json:"exceptionDates"`
+}
+
+// DeleteScheduleRequest is the parameter struct to the DeleteSchedule function.
+type DeleteScheduleRequest struct {
+	CourseID   string `json:"courseID"`
+	ScheduleID string `json:"scheduleID"`
+}
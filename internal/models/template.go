@@ -0,0 +1,92 @@
+package models
+
+var (
+	FirestoreQueueTemplatesCollection = "queueTemplates"
+)
+
+// QueueTemplate is a named set of queue settings that a course's staff can create queues from.
+type QueueTemplate struct {
+	ID                 string         `json:"id" mapstructure:"id"`
+	CourseID           string         `json:"courseID" mapstructure:"courseID"`
+	Name               string         `json:"name" mapstructure:"name"`
+	Title              string         `json:"title" mapstructure:"title"`
+	Description        string         `json:"description" mapstructure:"description"`
+	Location           string         `json:"location" mapstructure:"location"`
+	ShowMeetingLinks   bool           `json:"showMeetingLinks" mapstructure:"showMeetingLinks"`
+	AllowTicketEditing bool           `json:"allowTicketEditing" mapstructure:"allowTicketEditing"`
+	FaceMaskPolicy     MaskPolicy     `json:"faceMaskPolicy" mapstructure:"faceMaskPolicy"`
+	RejoinCooldown     int            `json:"rejoinCooldown" mapstructure:"rejoinCooldown"`
+	OrderingPolicy     OrderingPolicy `json:"orderingPolicy" mapstructure:"orderingPolicy"`
+	Categories         []string       `json:"categories" mapstructure:"categories"`
+}
+
+// CreateQueueRequest returns a request to create a queue with the template's settings.
+func (t *QueueTemplate) CreateQueueRequest() CreateQueueRequest {
+	return CreateQueueRequest{
+		Title:              t.Title,
+		Description:        t.Description,
+		Location:           t.Location,
+		ShowMeetingLinks:   t.ShowMeetingLinks,
+		AllowTicketEditing: t.AllowTicketEditing,
+		CourseID:           t.CourseID,
+		FaceMaskPolicy:     t.FaceMaskPolicy,
+		RejoinCooldown:     t.RejoinCooldown,
+		OrderingPolicy:     t.OrderingPolicy,
+		Categories:         append([]string(nil), t.Categories...),
+		TemplateID:         t.ID,
+	}
+}
+
+// CloneRequest returns a request to create a copy of the template in another course.
+func (t *QueueTemplate) CloneRequest(courseID string) CreateQueueTemplateRequest {
+	return CreateQueueTemplateRequest{
+		CourseID:           courseID,
+		Name:               t.Name,
+		Title:              t.Title,
+		Description:        t.Description,
+		Location:           t.Location,
+		ShowMeetingLinks:   t.ShowMeetingLinks,
+		AllowTicketEditing: t.AllowTicketEditing,
+		FaceMaskPolicy:     t.FaceMaskPolicy,
+		RejoinCooldown:     t.RejoinCooldown,
+		OrderingPolicy:     t.OrderingPolicy,
+		Categories:         append([]string(nil), t.Categories...),
+	}
+}
+
+// CreateQueueTemplateRequest is the parameter struct to the CreateQueueTemplate function.
+type CreateQueueTemplateRequest struct {
+	CourseID           string         `json:"courseID"`
+	Name               string         `json:"name"`
+	Title              string         `json:"title"`
+	Description        string         `json:"description"`
+	Location           string         `json:"location"`
+	ShowMeetingLinks   bool           `json:"showMeetingLinks"`
+	AllowTicketEditing bool           `json:"allowTicketEditing"`
+	FaceMaskPolicy     MaskPolicy     `json:"faceMaskPolicy"`
+	RejoinCooldown     int            `json:"rejoinCooldown"`
+	OrderingPolicy     OrderingPolicy `json:"orderingPolicy"`
+	Categories         []string       `json:"categories"`
+}
+
+// EditQueueTemplateRequest is the parameter struct to the EditQueueTemplate function.
+type EditQueueTemplateRequest struct {
+	CourseID           string         `json:"courseID"`
+	TemplateID         string         `json:"templateID"`
+	Name               string         `json:"name"`
+	Title              string         `json:"title"`
+	Description        string         `json:"description"`
+	Location           string         `json:"location"`
+	ShowMeetingLinks   bool           `json:"showMeetingLinks"`
+	AllowTicketEditing bool           `json:"allowTicketEditing"`
+	FaceMaskPolicy     MaskPolicy     `json:"faceMaskPolicy"`
+	RejoinCooldown     int            `json:"rejoinCooldown"`
+	OrderingPolicy     OrderingPolicy `json:"orderingPolicy"`
+	Categories         []string       `json:"categories"`
+}
+
+// DeleteQueueTemplateRequest is the parameter struct to the DeleteQueueTemplate function.
+type DeleteQueueTemplateRequest struct {
+	CourseID   string `json:"courseID"`
+	TemplateID string `json:"templateID"`
+}
@@ -1,13 +1,24 @@
 package qerrors
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	// Generic errors
 	InvalidBody = errors.New("invalid body")
 
 	// Course errors
-	CourseNotFoundError = errors.New("course not found")
+	CourseNotFoundError      = errors.New("course not found")
+	InvalidPermissionError   = errors.New("course permissions must be ADMIN, STAFF or STUDENT")
+	NotEnrolledError         = errors.New("you are not enrolled in this course")
+	InviteNotFoundError      = errors.New("invite not found")
+	BulkUploadHeaderError    = errors.New("bulk upload data must have email, role, course_code and course_name columns")
+	InvalidRolloverError     = errors.New("a rollover must be from one term to a different one")
+	CourseArchivedError      = errors.New("the course is archived")
+	InvalidCourseCursorError = errors.New("invalid course cursor")
+	InvalidCourseSortError   = errors.New("courses can only be sorted by code, title or term")
 
 	// User errors
 	DeleteUserError    = errors.New("an error occurred while deleting user")
@@ -15,10 +26,47 @@ var (
 	InvalidEmailError  = errors.New("invalid Brown email address")
 	InvalidDisplayName = errors.New("invalid display name provided")
 
+	// Schedule errors
+	InvalidScheduleError       = errors.New("the provided schedule is not valid")
+	ScheduleNotFoundError      = errors.New("schedule not found")
+	NoScheduledSessionError    = errors.New("no session of the schedule is in progress")
+	SessionAlreadyStartedError = errors.New("a queue has already been created for this session")
+
+	// Queue template errors
+	InvalidQueueTemplateError  = errors.New("a queue template must have a name")
+	QueueTemplateNotFoundError = errors.New("queue template not found")
+
+	// Audit log errors
+	AuditCursorNotFoundError = errors.New("audit log cursor not found")
+
 	// Queue errors
-	InvalidQueueError  = errors.New("the provided queue is not valid")
-	InvalidTicketError = errors.New("the provided ticket is not valid")
-	QueueCooldownError = errors.New("user already made a ticket within the last 15 minutes")
-	ActiveTicketError  = errors.New("User already has an active ticket in queue")
-	QueueNotFoundError = errors.New("queue not found")
+	InvalidQueueError          = errors.New("the provided queue is not valid")
+	InvalidTicketError         = errors.New("the provided ticket is not valid")
+	TicketNotFoundError        = errors.New("ticket not found")
+	QueueCooldownError         = errors.New("user already made a ticket within the last 15 minutes")
+	ActiveTicketError          = errors.New("User already has an active ticket in queue")
+	QueueNotFoundError         = errors.New("queue not found")
+	InvalidCommandError        = errors.New("unknown ticket command")
+	InvalidCategoryError       = errors.New("the provided category is not one of the queue's categories")
+	MissingCategoryError       = errors.New("a category is required for tickets in this queue")
+	InvalidCategoriesError     = errors.New("queue categories must be unique and non-empty")
+	NoWaitingTicketsError      = errors.New("there are no waiting tickets")
+	QueueClosedError           = errors.New("the queue is closed to new tickets")
+	QueueNotEndedError         = errors.New("the queue is not open past its end time")
+	InvalidOrderingPolicyError = errors.New("unknown queue ordering policy")
 )
+
+// TicketTransitionError is returned when a ticket cannot move from one status to another.
+type TicketTransitionError struct {
+	From string
+	To   string
+	// Forbidden is true if the move is allowed, but not for the user who attempted it.
+	Forbidden bool
+}
+
+func (e *TicketTransitionError) Error() string {
+	if e.Forbidden {
+		return fmt.Sprintf("you may not move a ticket from %s to %s", e.From, e.To)
+	}
+	return fmt.Sprintf("a ticket cannot move from %s to %s", e.From, e.To)
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"signmeup/internal/models"
+	"sort"
+	"time"
+)
+
+// GetCourseAnalytics summarizes the tickets created in every queue of a course during the requested period.
+func (fr *FirebaseRepository) GetCourseAnalytics(c *models.GetCourseAnalyticsRequest) (*models.CourseAnalytics, error) {
+	if _, err := fr.GetCourseByID(c.CourseID); err != nil {
+		return nil, err
+	}
+
+	queues, err := fr.GetCourseQueues(c.CourseID)
+	if err != nil {
+		return nil, err
+	}
+
+	var tickets []*models.Ticket
+	for _, queue := range queues {
+		queueTickets, err := fr.GetTickets(queue.ID, c.From, c.To)
+		if err != nil {
+			return nil, err
+		}
+		tickets = append(tickets, queueTickets...)
+	}
+
+	return courseAnalytics(c, tickets), nil
+}
+
+// courseAnalytics summarizes tickets, which must all have been created in the requested period.
+func courseAnalytics(c *models.GetCourseAnalyticsRequest, tickets []*models.Ticket) *models.CourseAnalytics {
+	analytics := &models.CourseAnalytics{
+		CourseID:     c.CourseID,
+		From:         c.From,
+		To:           c.To,
+		TotalTickets: len(tickets),
+		StatusCounts: make(map[models.TicketStatus]int),
+		StaffLoad:    make([]models.StaffLoad, 0),
+		BusiestHours: make([]models.HourCount, 0),
+	}
+
+	location := c.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	var waits, services []time.Duration
+	staff := make(map[string]int)
+	hours := make(map[int]int)
+	visits := make(map[string]int)
+	for _, t := range tickets {
+		analytics.StatusCounts[t.Status]++
+		hours[t.CreatedAt.In(location).Hour()]++
+		visits[t.User.UserID]++
+
+		if t.ClaimedAt.IsZero() {
+			continue
+		}
+		waits = append(waits, t.ClaimedAt.Sub(t.CreatedAt))
+		if t.ClaimedBy != "" {
+			staff[t.ClaimedBy]++
+		}
+		if t.Status == models.StatusComplete && t.CompletedAt.After(t.ClaimedAt) {
+			services = append(services, t.CompletedAt.Sub(t.ClaimedAt))
+		}
+	}
+
+	analytics.WaitTime = durationStats(waits)
+	analytics.ServiceTime = durationStats(services)
+
+	for userID, n := range staff {
+		analytics.StaffLoad = append(analytics.StaffLoad, models.StaffLoad{UserID: userID, Tickets: n})
+	}
+	sort.Slice(analytics.StaffLoad, func(i, j int) bool {
+		a, b := analytics.StaffLoad[i], analytics.StaffLoad[j]
+		return a.Tickets > b.Tickets || (a.Tickets == b.Tickets && a.UserID < b.UserID)
+	})
+
+	for hour, n := range hours {
+		analytics.BusiestHours = append(analytics.BusiestHours, models.HourCount{Hour: hour, Tickets: n})
+	}
+	sort.Slice(analytics.BusiestHours, func(i, j int) bool {
+		a, b := analytics.BusiestHours[i], analytics.BusiestHours[j]
+		return a.Tickets > b.Tickets || (a.Tickets == b.Tickets && a.Hour < b.Hour)
+	})
+
+	analytics.UniqueStudents = len(visits)
+	for _, n := range visits {
+		if n > 1 {
+			analytics.RepeatVisitors++
+		}
+	}
+
+	return analytics
+}
+
+// durationStats computes the median and 90th percentile of durations, using the nearest-rank method.
+func durationStats(durations []time.Duration) models.DurationStats {
+	stats := models.DurationStats{Count: len(durations)}
+	if len(durations) == 0 {
+		return stats
+	}
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	percentile := func(p int) int64 {
+		rank := (p*len(durations) + 99) / 100
+		return int64(durations[rank-1].Seconds())
+	}
+	stats.MedianSeconds = percentile(50)
+	stats.P90Seconds = percentile(90)
+	return stats
+}
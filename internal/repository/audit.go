@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"fmt"
+	"signmeup/internal/firebase"
+	"signmeup/internal/models"
+	"signmeup/internal/qerrors"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/golang/glog"
+	"github.com/mitchellh/mapstructure"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// defaultAuditPageSize is the number of audit log entries returned when a request does not give a limit, and
+	// maxAuditPageSize is the most that can be requested at once.
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 200
+)
+
+// AddAuditLog records an entry in the audit log, setting its ID and timestamp.
+func (fr *FirebaseRepository) AddAuditLog(entry *models.AuditLogEntry) error {
+	entry.Timestamp = time.Now()
+	ref, _, err := fr.firestoreClient.Collection(models.FirestoreAuditLogsCollection).Add(firebase.Context, map[string]interface{}{
+		"action":     entry.Action,
+		"actorID":    entry.ActorID,
+		"actorEmail": entry.ActorEmail,
+		"courseID":   entry.CourseID,
+		"target":     entry.Target,
+		"diff":       auditDiffDocument(entry.Diff),
+		"timestamp":  entry.Timestamp,
+	})
+	if err != nil {
+		return fmt.Errorf("error creating audit log entry: %v", err)
+	}
+
+	entry.ID = ref.ID
+	return nil
+}
+
+// recordAudit adds an entry to the audit log after the change it describes has been made. The change cannot be undone
+// at that point, so a failure to record it is logged rather than returned.
+func (fr *FirebaseRepository) recordAudit(entry *models.AuditLogEntry) {
+	if err := fr.AddAuditLog(entry); err != nil {
+		glog.Errorf("%v: %+v\n", err, entry)
+	}
+}
+
+// GetAuditLogs gets a page of the audit log, newest first. Combining filters requires a matching composite index on
+// the audit log collection.
+func (fr *FirebaseRepository) GetAuditLogs(c *models.GetAuditLogsRequest) (*models.AuditLogPage, error) {
+	collection := fr.firestoreClient.Collection(models.FirestoreAuditLogsCollection)
+
+	query := collection.Query
+	if c.CourseID != "" {
+		query = query.Where("courseID", "==", c.CourseID)
+	}
+	if c.ActorID != "" {
+		query = query.Where("actorID", "==", c.ActorID)
+	}
+	if c.Action != "" {
+		query = query.Where("action", "==", c.Action)
+	}
+	query = query.OrderBy("timestamp", firestore.Desc)
+
+	if c.Cursor != "" {
+		cursor, err := collection.Doc(c.Cursor).Get(firebase.Context)
+		if status.Code(err) == codes.NotFound {
+			return nil, qerrors.AuditCursorNotFoundError
+		} else if err != nil {
+			return nil, err
+		}
+		query = query.StartAfter(cursor)
+	}
+
+	limit := auditPageSize(c.Limit)
+	docs, err := query.Limit(limit).Documents(firebase.Context).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*models.AuditLogEntry, 0, len(docs))
+	for _, doc := range docs {
+		var entry models.AuditLogEntry
+		err = mapstructure.Decode(doc.Data(), &entry)
+		if err != nil {
+			return nil, err
+		}
+		entry.ID = doc.Ref.ID
+		entries = append(entries, &entry)
+	}
+	return newAuditLogPage(entries, limit), nil
+}
+
+// newAuditLogEntry describes an action taken by actor.
+func newAuditLogEntry(action models.AuditAction, actor *models.User, courseID string, target string,
+	diff map[string]models.AuditChange) *models.AuditLogEntry {
+	entry := &models.AuditLogEntry{
+		Action:   action,
+		CourseID: courseID,
+		Target:   target,
+		Diff:     diff,
+	}
+	if actor != nil {
+		entry.ActorID = actor.ID
+		entry.ActorEmail = actor.Email
+	}
+	return entry
+}
+
+// addAuditChange adds a field to diff if its value changed.
+func addAuditChange(diff map[string]models.AuditChange, field string, oldValue interface{}, newValue interface{}) {
+	if oldValue != newValue {
+		diff[field] = models.AuditChange{Old: oldValue, New: newValue}
+	}
+}
+
+// courseEditDiff returns the fields of course that an edit changes.
+func courseEditDiff(course *models.Course, c *models.EditCourseRequest) map[string]models.AuditChange {
+	diff := make(map[string]models.AuditChange)
+	addAuditChange(diff, "title", course.Title, c.Title)
+	addAuditChange(diff, "code", course.Code, c.Code)
+	addAuditChange(diff, "term", course.Term, c.Term)
+	if c.RestrictToEnrolled != nil {
+		addAuditChange(diff, "restrictToEnrolled", course.RestrictToEnrolled, *c.RestrictToEnrolled)
+	}
+	return diff
+}
+
+// courseDeleteDiff describes the deletion of course.
+func courseDeleteDiff(course *models.Course) map[string]models.AuditChange {
+	return map[string]models.AuditChange{
+		"title": {Old: course.Title},
+		"code":  {Old: course.Code},
+		"term":  {Old: course.Term},
+	}
+}
+
+// courseArchiveAction returns the action that archiving or unarchiving a course is recorded as.
+func courseArchiveAction(archived bool) models.AuditAction {
+	if archived {
+		return models.AuditArchiveCourse
+	}
+	return models.AuditUnarchiveCourse
+}
+
+// coursePermission returns the permission a user has in course, or nil if they have none.
+func coursePermission(course *models.Course, userID string) interface{} {
+	if permission, ok := course.CoursePermissions[userID]; ok {
+		return string(permission)
+	}
+	return nil
+}
+
+// auditDiffDocument converts a diff to the form stored in Firestore.
+func auditDiffDocument(diff map[string]models.AuditChange) map[string]interface{} {
+	doc := make(map[string]interface{}, len(diff))
+	for field, change := range diff {
+		doc[field] = map[string]interface{}{
+			"old": change.Old,
+			"new": change.New,
+		}
+	}
+	return doc
+}
+
+// auditPageSize clamps a requested page size to the allowed range.
+func auditPageSize(limit int) int {
+	if limit <= 0 {
+		return defaultAuditPageSize
+	}
+	if limit > maxAuditPageSize {
+		return maxAuditPageSize
+	}
+	return limit
+}
+
+// newAuditLogPage wraps a page of at most limit entries. A full page may be followed by more entries, so its last entry
+// is the cursor for the next page.
+func newAuditLogPage(entries []*models.AuditLogEntry, limit int) *models.AuditLogPage {
+	page := &models.AuditLogPage{Entries: entries}
+	if len(entries) == limit {
+		page.NextCursor = entries[len(entries)-1].ID
+	}
+	return page
+}
@@ -7,7 +7,6 @@ import (
 	"signmeup/internal/firebase"
 	"signmeup/internal/models"
 	"signmeup/internal/qerrors"
-	"strings"
 
 	"cloud.google.com/go/firestore"
 	"github.com/mitchellh/mapstructure"
@@ -63,19 +62,21 @@ func (fr *FirebaseRepository) GetCourseByInfo(code string, term string) (*models
 
 func (fr *FirebaseRepository) CreateCourse(c *models.CreateCourseRequest) (course *models.Course, err error) {
 	course = &models.Course{
-		Title:             c.Title,
-		Code:              c.Code,
-		Term:              c.Term,
-		IsArchived:        false,
-		CoursePermissions: map[string]models.CoursePermission{},
+		Title:              c.Title,
+		Code:               c.Code,
+		Term:               c.Term,
+		IsArchived:         false,
+		CoursePermissions:  map[string]models.CoursePermission{},
+		RestrictToEnrolled: c.RestrictToEnrolled,
 	}
 
 	ref, _, err := fr.firestoreClient.Collection(models.FirestoreCoursesCollection).Add(firebase.Context, map[string]interface{}{
-		"title":             course.Title,
-		"code":              course.Code,
-		"term":              course.Term,
-		"isArchived":        course.IsArchived,
-		"coursePermissions": course.CoursePermissions,
+		"title":              course.Title,
+		"code":               course.Code,
+		"term":               course.Term,
+		"isArchived":         course.IsArchived,
+		"coursePermissions":  course.CoursePermissions,
+		"restrictToEnrolled": course.RestrictToEnrolled,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("error creating course: %v\n", err)
@@ -105,30 +106,76 @@ func (fr *FirebaseRepository) DeleteCourse(c *models.DeleteCourseRequest) error
 		}
 	}
 
+	// Delete the course's templates and schedules, which Firestore does not delete with the course.
+	courseRef := fr.firestoreClient.Collection(models.FirestoreCoursesCollection).Doc(c.CourseID)
+	for _, collection := range []string{models.FirestoreQueueTemplatesCollection, models.FirestoreSchedulesCollection} {
+		if err := deleteCollection(courseRef.Collection(collection)); err != nil {
+			return err
+		}
+	}
+
 	// Delete the course.
-	_, err = fr.firestoreClient.Collection(models.FirestoreCoursesCollection).Doc(c.CourseID).Delete(firebase.Context)
-	return err
+	_, err = courseRef.Delete(firebase.Context)
+	if err != nil {
+		return err
+	}
+
+	fr.recordAudit(newAuditLogEntry(models.AuditDeleteCourse, c.Actor, course.ID, course.ID, courseDeleteDiff(course)))
+	return nil
+}
+
+// deleteCollection deletes the documents of a collection.
+func deleteCollection(collection *firestore.CollectionRef) error {
+	docs, err := collection.Documents(firebase.Context).GetAll()
+	if err != nil {
+		return err
+	}
+
+	for _, doc := range docs {
+		if _, err := doc.Ref.Delete(firebase.Context); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (fr *FirebaseRepository) EditCourse(c *models.EditCourseRequest) error {
-	_, err := fr.firestoreClient.Collection(models.FirestoreCoursesCollection).Doc(c.CourseID).Update(firebase.Context, []firestore.Update{
+	course, err := fr.GetCourseByID(c.CourseID)
+	if err != nil {
+		return err
+	}
+
+	updates := []firestore.Update{
 		{Path: "title", Value: c.Title},
 		{Path: "term", Value: c.Term},
 		{Path: "code", Value: c.Code},
-	})
-	return err
+	}
+	if c.RestrictToEnrolled != nil {
+		updates = append(updates, firestore.Update{Path: "restrictToEnrolled", Value: *c.RestrictToEnrolled})
+	}
+
+	_, err = fr.firestoreClient.Collection(models.FirestoreCoursesCollection).Doc(c.CourseID).Update(firebase.Context, updates)
+	if err != nil {
+		return err
+	}
+
+	fr.recordAudit(newAuditLogEntry(models.AuditEditCourse, c.Actor, course.ID, course.ID, courseEditDiff(course, c)))
+	return nil
 }
 
 func (fr *FirebaseRepository) AddPermission(c *models.AddCoursePermissionRequest) error {
+	if err := validCoursePermission(c.Permission); err != nil {
+		return err
+	}
+
 	// Get user by email.
 	user, err := fr.GetUserByEmail(c.Email)
 	if err != nil {
-		// The user doesn't exist; add an invite to the invites collection and then return.
-		_, _, err = fr.firestoreClient.Collection(models.FirestoreInvitesCollection).Add(firebase.Context, map[string]interface{}{
-			"email":      c.Email,
-			"courseID":   c.CourseID,
-			"permission": c.Permission,
-		})
+		// The user doesn't exist; invite them instead.
+		return fr.inviteToCourse(c)
+	}
+	course, err := fr.GetCourseByID(c.CourseID)
+	if err != nil {
 		return err
 	}
 	// Set course-side permissions.
@@ -148,11 +195,30 @@ func (fr *FirebaseRepository) AddPermission(c *models.AddCoursePermissionRequest
 			Value: c.Permission,
 		},
 	})
-	return err
+	if err != nil {
+		return err
+	}
+
+	diff := make(map[string]models.AuditChange)
+	addAuditChange(diff, "permission", coursePermission(course, user.ID), c.Permission)
+	fr.recordAudit(newAuditLogEntry(models.AuditAddPermission, c.Actor, c.CourseID, normalizeEmail(c.Email), diff))
+	return nil
 }
 
 func (fr *FirebaseRepository) RemovePermission(c *models.RemoveCoursePermissionRequest) error {
-	_, err := fr.firestoreClient.Collection(models.FirestoreCoursesCollection).Doc(c.CourseID).Update(firebase.Context, []firestore.Update{
+	course, err := fr.GetCourseByID(c.CourseID)
+	if err != nil {
+		return err
+	}
+
+	// The removal is audited under the user's email, as AddPermission is, so that the two can be matched. The user's ID
+	// is used instead if their profile has been deleted.
+	target := c.UserID
+	if profile, err := fr.getUserProfile(c.UserID); err == nil {
+		target = normalizeEmail(profile.Email)
+	}
+
+	_, err = fr.firestoreClient.Collection(models.FirestoreCoursesCollection).Doc(c.CourseID).Update(firebase.Context, []firestore.Update{
 		{
 			Path:  "coursePermissions." + c.UserID,
 			Value: firestore.Delete,
@@ -167,62 +233,202 @@ func (fr *FirebaseRepository) RemovePermission(c *models.RemoveCoursePermissionR
 			Value: firestore.Delete,
 		},
 	})
-	return err
+	if err != nil {
+		return err
+	}
+
+	fr.recordAudit(newAuditLogEntry(models.AuditRemovePermission, c.Actor, c.CourseID, target, map[string]models.AuditChange{
+		"permission": {Old: coursePermission(course, c.UserID)},
+	}))
+	return nil
+}
+
+func (fr *FirebaseRepository) BulkUpload(c *models.BulkUploadRequest) (*models.BulkUploadReport, error) {
+	return bulkUpload(fr, c)
 }
 
-func (fr *FirebaseRepository) BulkUpload(c *models.BulkUploadRequest) error {
-	// SCHEMA: (email, [UTA/HTA], course_code, course_name)
+func (fr *FirebaseRepository) ImportRoster(c *models.ImportRosterRequest) (*models.RosterImportResult, error) {
+	return importRoster(fr, c)
+}
 
-	// Extract data.
-	data := make([][]string, 0)
-	for _, row := range strings.Split(c.Data, "\n") {
-		// Split cols, trim fields, lowercase all but course name.
-		cols := strings.Split(row, ",")
-		for i := range cols {
-			cols[i] = strings.TrimSpace(cols[i])
-			if i != 3 {
-				cols[i] = strings.ToLower(cols[i])
+// importRoster enrolls each email on a course roster as a student using the given repository. Emails without accounts
+// are invited, as with AddPermission, and users who already have a role in the course keep it.
+func importRoster(r Repository, c *models.ImportRosterRequest) (*models.RosterImportResult, error) {
+	course, err := r.GetCourseByID(c.CourseID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.RosterImportResult{Enrolled: []string{}, Invited: []string{}, Skipped: []string{}}
+	seen := make(map[string]bool)
+	for _, email := range c.Emails {
+		email = normalizeEmail(email)
+		if email == "" || seen[email] {
+			continue
+		}
+		seen[email] = true
+
+		// As with AddPermission, an email that cannot be found is invited.
+		user, err := r.GetUserByEmail(email)
+		exists := err == nil
+		if exists {
+			if _, ok := course.CoursePermissions[user.ID]; ok {
+				result.Skipped = append(result.Skipped, email)
+				continue
 			}
 		}
-		// Map permissions.
-		if cols[1] == "hta" {
-			cols[1] = string(models.CourseAdmin)
+
+		err = r.AddPermission(&models.AddCoursePermissionRequest{
+			CourseID:   c.CourseID,
+			Email:      email,
+			Permission: string(models.CourseStudent),
+			Actor:      c.Actor,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if exists {
+			result.Enrolled = append(result.Enrolled, email)
 		} else {
-			cols[1] = string(models.CourseStaff)
+			result.Invited = append(result.Invited, email)
 		}
-		data = append(data, cols)
 	}
+	return result, nil
+}
 
-	// Create courses.
-	courses := make(map[string]string)
-	for _, row := range data {
-		courseCode := row[2]
-		courseName := row[3]
-		courses[courseCode] = courseName
+// validCoursePermission returns an error if permission is not a course role.
+func validCoursePermission(permission string) error {
+	switch models.CoursePermission(permission) {
+	case models.CourseAdmin, models.CourseStaff, models.CourseStudent:
+		return nil
+	default:
+		return qerrors.InvalidPermissionError
 	}
-	for code, name := range courses {
-		_, _ = fr.CreateCourse(&models.CreateCourseRequest{
-			Title: name,
-			Code:  code,
-			Term:  c.Term,
-		})
+}
+
+// GetCoursesByTerm gets the courses of a term, ordered by code.
+func (fr *FirebaseRepository) GetCoursesByTerm(term string) ([]*models.Course, error) {
+	docs, err := fr.firestoreClient.Collection(models.FirestoreCoursesCollection).Where("term", "==", term).
+		Documents(firebase.Context).GetAll()
+	if err != nil {
+		return nil, err
 	}
 
-	// Create invites.
-	for _, row := range data {
-		course, err := fr.GetCourseByInfo(row[2], c.Term)
+	courses := make([]*models.Course, 0, len(docs))
+	for _, doc := range docs {
+		var c models.Course
+		err = mapstructure.Decode(doc.Data(), &c)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		_ = fr.AddPermission(&models.AddCoursePermissionRequest{
-			CourseID:   course.ID,
-			Email:      row[0],
-			Permission: row[1],
-		})
+		c.ID = doc.Ref.ID
+		courses = append(courses, &c)
+	}
+	sortCoursesByCode(courses)
+	return courses, nil
+}
+
+// ListCourses gets a page of the courses matching a ListCoursesRequest. The term and archived filters are applied by
+// Firestore, unless the listing is limited to given courses, and the rest are applied in memory.
+func (fr *FirebaseRepository) ListCourses(c *models.ListCoursesRequest) (*models.CoursePage, error) {
+	collection := fr.firestoreClient.Collection(models.FirestoreCoursesCollection)
+
+	var docs []*firestore.DocumentSnapshot
+	var err error
+	if c.CourseIDs != nil {
+		refs := make([]*firestore.DocumentRef, 0, len(c.CourseIDs))
+		for _, courseID := range c.CourseIDs {
+			refs = append(refs, collection.Doc(courseID))
+		}
+		if len(refs) > 0 {
+			docs, err = fr.firestoreClient.GetAll(firebase.Context, refs)
+		}
+	} else {
+		query := collection.Query
+		if c.Term != "" {
+			query = query.Where("term", "==", c.Term)
+		}
+		if c.Archived != nil {
+			query = query.Where("isArchived", "==", *c.Archived)
+		}
+		docs, err = query.Documents(firebase.Context).GetAll()
+	}
+	if err != nil {
+		return nil, err
 	}
+
+	courses := make([]*models.Course, 0, len(docs))
+	for _, doc := range docs {
+		// GetAll returns snapshots of courses that do not exist, such as deleted courses that users still have
+		// permissions for.
+		if !doc.Exists() {
+			continue
+		}
+
+		var course models.Course
+		err = mapstructure.Decode(doc.Data(), &course)
+		if err != nil {
+			return nil, err
+		}
+		course.ID = doc.Ref.ID
+		courses = append(courses, &course)
+	}
+	return listCourses(courses, c)
+}
+
+func (fr *FirebaseRepository) SetCourseArchived(c *models.SetCourseArchivedRequest) error {
+	course, err := fr.GetCourseByID(c.CourseID)
+	if err != nil {
+		return err
+	}
+	if course.IsArchived == c.Archived {
+		return nil
+	}
+
+	_, err = fr.firestoreClient.Collection(models.FirestoreCoursesCollection).Doc(c.CourseID).Update(firebase.Context, []firestore.Update{
+		{Path: "isArchived", Value: c.Archived},
+	})
+	if err != nil {
+		return err
+	}
+
+	fr.recordAudit(newAuditLogEntry(courseArchiveAction(c.Archived), c.Actor, course.ID, course.ID, map[string]models.AuditChange{
+		"isArchived": {Old: course.IsArchived, New: c.Archived},
+	}))
 	return nil
 }
 
+func (fr *FirebaseRepository) RolloverTerm(c *models.RolloverTermRequest) (*models.RolloverReport, error) {
+	return rolloverTerm(fr, c)
+}
+
+func (fr *FirebaseRepository) ArchiveCoursesByTerm(c *models.ArchiveCoursesByTermRequest) (int, error) {
+	return archiveCoursesByTerm(fr, c)
+}
+
+// archiveCoursesByTerm archives the courses of a term using the given repository, returning how many were not already
+// archived. Unlike DeleteCoursesByTerm, this can be undone by unarchiving the courses.
+func archiveCoursesByTerm(r Repository, c *models.ArchiveCoursesByTermRequest) (int, error) {
+	courses, err := r.GetCoursesByTerm(c.Term)
+	if err != nil {
+		return 0, err
+	}
+
+	archived := 0
+	for _, course := range courses {
+		if course.IsArchived {
+			continue
+		}
+		err = r.SetCourseArchived(&models.SetCourseArchivedRequest{CourseID: course.ID, Archived: true, Actor: c.Actor})
+		if err != nil {
+			return archived, err
+		}
+		archived++
+	}
+	return archived, nil
+}
+
 // DeleteCoursesByTerm deletes all courses within the given term.
 func (fr *FirebaseRepository) DeleteCoursesByTerm(term string) error {
 	iter := fr.firestoreClient.Collection(models.FirestoreCoursesCollection).Where("term", "==", term).Documents(firebase.Context)
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"signmeup/internal/firebase"
+	"signmeup/internal/models"
+)
+
+func TestFirebaseDeleteCourseDeletesSubcollections(t *testing.T) {
+	repo := newEmulatorRepository(t)
+	course, err := repo.CreateCourse(&models.CreateCourseRequest{Title: "Intro", Code: "cs0150", Term: "fall"})
+	if err != nil {
+		t.Fatalf("creating course: %v", err)
+	}
+	_, err = repo.CreateQueueTemplate(&models.CreateQueueTemplateRequest{CourseID: course.ID, Name: "Hours", Title: "Hours"})
+	if err != nil {
+		t.Fatalf("creating template: %v", err)
+	}
+	_, err = repo.CreateSchedule(&models.CreateScheduleRequest{
+		CourseID:  course.ID,
+		Title:     "Hours",
+		Weekday:   time.Monday,
+		StartTime: "10:00",
+		EndTime:   "12:00",
+		TimeZone:  "America/New_York",
+	})
+	if err != nil {
+		t.Fatalf("creating schedule: %v", err)
+	}
+
+	if err := repo.DeleteCourse(&models.DeleteCourseRequest{CourseID: course.ID}); err != nil {
+		t.Fatalf("deleting course: %v", err)
+	}
+
+	courseRef := repo.firestoreClient.Collection(models.FirestoreCoursesCollection).Doc(course.ID)
+	for _, collection := range []string{models.FirestoreQueueTemplatesCollection, models.FirestoreSchedulesCollection} {
+		docs, err := courseRef.Collection(collection).Documents(firebase.Context).GetAll()
+		if err != nil {
+			t.Fatalf("getting %s: %v", collection, err)
+		}
+		if len(docs) != 0 {
+			t.Errorf("deleted course has %d %s, want 0", len(docs), collection)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"signmeup/internal/models"
+	"sort"
+	"time"
+)
+
+const (
+	// serviceTimeSampleSize is how many of a queue's most recently completed tickets service times are averaged over.
+	serviceTimeSampleSize = 20
+	// defaultServiceTime is assumed when a queue has no completed tickets to estimate from.
+	defaultServiceTime = 10 * time.Minute
+	// activeStaffWindow is how recently a staff member must have claimed a ticket to count as helping students.
+	activeStaffWindow = 30 * time.Minute
+)
+
+// estimateWait estimates the wait of each pending ticket, in order, from the queue's recently completed tickets. It
+// assumes each active staff member helps one student at a time.
+func estimateWait(queueID string, pending []*models.Ticket, completed []*models.Ticket, now time.Time) *models.WaitEstimate {
+	estimate := &models.WaitEstimate{QueueID: queueID, Tickets: make([]models.TicketWaitEstimate, 0, len(pending))}
+
+	// Average the service times of the most recently completed tickets.
+	recent := make([]*models.Ticket, 0, len(completed))
+	for _, t := range completed {
+		if !t.ClaimedAt.IsZero() && t.CompletedAt.After(t.ClaimedAt) {
+			recent = append(recent, t)
+		}
+	}
+	sortTicketsByCompletion(recent)
+	if len(recent) > serviceTimeSampleSize {
+		recent = recent[:serviceTimeSampleSize]
+	}
+
+	serviceTime := defaultServiceTime
+	if len(recent) > 0 {
+		var total time.Duration
+		for _, t := range recent {
+			total += t.CompletedAt.Sub(t.ClaimedAt)
+		}
+		serviceTime = total / time.Duration(len(recent))
+	}
+	estimate.AverageServiceSeconds = int64(serviceTime.Seconds())
+	estimate.SampleSize = len(recent)
+
+	// Count the staff members currently helping a student or who recently finished helping one.
+	staff := make(map[string]bool)
+	for _, t := range pending {
+		if t.Status == models.StatusClaimed && t.ClaimedBy != "" {
+			staff[t.ClaimedBy] = true
+		}
+	}
+	for _, t := range completed {
+		if t.ClaimedBy != "" && now.Sub(t.CompletedAt) < activeStaffWindow {
+			staff[t.ClaimedBy] = true
+		}
+	}
+	estimate.ActiveStaff = len(staff)
+	servers := estimate.ActiveStaff
+	if servers == 0 {
+		servers = 1
+	}
+
+	wait := func(ahead int) int64 {
+		return int64((serviceTime * time.Duration(ahead) / time.Duration(servers)).Seconds())
+	}
+
+	position := 0
+	for _, t := range pending {
+		if t.Status == models.StatusClaimed {
+			estimate.Tickets = append(estimate.Tickets, models.TicketWaitEstimate{TicketID: t.ID})
+			continue
+		}
+
+		position++
+		estimate.Tickets = append(estimate.Tickets, models.TicketWaitEstimate{
+			TicketID:             t.ID,
+			Position:             position,
+			EstimatedWaitSeconds: wait(position - 1),
+		})
+	}
+	estimate.EstimatedWaitSeconds = wait(position)
+
+	return estimate
+}
+
+// sortTicketsByCompletion sorts tickets by when they were completed, most recent first.
+func sortTicketsByCompletion(tickets []*models.Ticket) {
+	sort.Slice(tickets, func(i, j int) bool {
+		return tickets[i].CompletedAt.After(tickets[j].CompletedAt)
+	})
+}
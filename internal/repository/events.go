@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"signmeup/internal/firebase"
+	"signmeup/internal/models"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/mitchellh/mapstructure"
+)
+
+// GetTicketEvents gets the history of a ticket, oldest first. The history of a deleted ticket is kept.
+func (fr *FirebaseRepository) GetTicketEvents(queueID string, ticketID string) ([]*models.TicketEvent, error) {
+	docs, err := fr.firestoreClient.Collection(models.FirestoreQueuesCollection).Doc(queueID).
+		Collection(models.FirestoreTicketsCollection).Doc(ticketID).
+		Collection(models.FirestoreTicketEventsCollection).OrderBy("timestamp", firestore.Asc).Documents(firebase.Context).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*models.TicketEvent, 0, len(docs))
+	for _, doc := range docs {
+		var event models.TicketEvent
+		err = mapstructure.Decode(doc.Data(), &event)
+		if err != nil {
+			return nil, err
+		}
+		event.ID = doc.Ref.ID
+		events = append(events, &event)
+	}
+	return events, nil
+}
+
+// createTicketEvent appends an event to the history of the ticket at ticketRef, as part of a transaction.
+func createTicketEvent(tx *firestore.Transaction, ticketRef *firestore.DocumentRef, event *models.TicketEvent) error {
+	ref := ticketRef.Collection(models.FirestoreTicketEventsCollection).NewDoc()
+	event.ID = ref.ID
+
+	return tx.Create(ref, map[string]interface{}{
+		"ticketID":       event.TicketID,
+		"type":           event.Type,
+		"actorID":        event.ActorID,
+		"timestamp":      event.Timestamp,
+		"from":           event.From,
+		"to":             event.To,
+		"oldDescription": event.OldDescription,
+		"newDescription": event.NewDescription,
+	})
+}
+
+// newTicketEvent describes a change to a ticket made by actor. The descriptions are only recorded if they differ.
+func newTicketEvent(ticketID string, eventType models.TicketEventType, actor *models.User, from models.TicketStatus,
+	to models.TicketStatus, oldDescription string, newDescription string) *models.TicketEvent {
+	event := &models.TicketEvent{
+		TicketID:  ticketID,
+		Type:      eventType,
+		Timestamp: time.Now(),
+		From:      from,
+		To:        to,
+	}
+	if actor != nil {
+		event.ActorID = actor.ID
+	}
+	if oldDescription != newDescription {
+		event.OldDescription = oldDescription
+		event.NewDescription = newDescription
+	}
+	return event
+}
@@ -1,8 +1,8 @@
 package repository
 
 import (
+	"context"
 	"fmt"
-	"signmeup/internal/firebase"
 	"sync"
 
 	"cloud.google.com/go/firestore"
@@ -12,18 +12,19 @@ import (
 )
 
 // createCollectionInitializer creates a snapshot iterator over the given collection, and when the
-// collection changes, runs a function.
-func (fr *FirebaseRepository) createCollectionInitializer(
+// collection changes, runs a function. It returns once ctx is done.
+func (fr *FirebaseRepository) createCollectionInitializer(ctx context.Context,
 	query firestore.Query, done *chan bool, handleDocs func(docs []*firestore.DocumentSnapshot) error) error {
 
-	it := query.Snapshots(firebase.Context)
+	it := query.Snapshots(ctx)
+	defer it.Stop()
 	var doOnce sync.Once
 
 	for {
 		snap, err := it.Next()
 
-		// DeadlineExceeded will be returned when ctx is cancelled.
-		if status.Code(err) == codes.DeadlineExceeded {
+		// DeadlineExceeded or Canceled will be returned when ctx is done.
+		if code := status.Code(err); code == codes.DeadlineExceeded || code == codes.Canceled {
 			return nil
 		} else if err != nil {
 			return fmt.Errorf("Snapshots.Next: %v", err)
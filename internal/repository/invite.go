@@ -0,0 +1,288 @@
+package repository
+
+import (
+	"fmt"
+	"signmeup/internal/firebase"
+	"signmeup/internal/models"
+	"signmeup/internal/qerrors"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/golang/glog"
+	"github.com/mitchellh/mapstructure"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetCourseInvites gets a course's pending invites, including expired ones that have not been redeemed.
+func (fr *FirebaseRepository) GetCourseInvites(courseID string) ([]*models.CourseInvite, error) {
+	docs, err := fr.firestoreClient.Collection(models.FirestoreInvitesCollection).Where("courseID", "==", courseID).
+		Documents(firebase.Context).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	invites, err := decodeInvites(docs)
+	if err != nil {
+		return nil, err
+	}
+	sortInvites(invites)
+	return invites, nil
+}
+
+// GetInvitesByEmail gets the unexpired invites for an email.
+func (fr *FirebaseRepository) GetInvitesByEmail(email string) ([]*models.CourseInvite, error) {
+	docs, err := fr.firestoreClient.Collection(models.FirestoreInvitesCollection).Where("email", "==", normalizeEmail(email)).
+		Documents(firebase.Context).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	invites, err := decodeInvites(docs)
+	if err != nil {
+		return nil, err
+	}
+	sortInvites(invites)
+	return unexpiredInvites(invites, time.Now()), nil
+}
+
+func (fr *FirebaseRepository) EditInvite(c *models.EditInviteRequest) error {
+	if err := validCoursePermission(c.Permission); err != nil {
+		return err
+	}
+
+	invite, err := fr.getCourseInvite(c.CourseID, c.InviteID)
+	if err != nil {
+		return err
+	}
+
+	updates := []firestore.Update{
+		{Path: "permission", Value: c.Permission},
+	}
+	expiresAt := invite.ExpiresAt
+	if c.ExpiresAt != nil {
+		expiresAt = *c.ExpiresAt
+		updates = append(updates, firestore.Update{Path: "expiresAt", Value: expiresAt})
+	}
+
+	_, err = fr.firestoreClient.Collection(models.FirestoreInvitesCollection).Doc(c.InviteID).Update(firebase.Context, updates)
+	if err != nil {
+		return err
+	}
+
+	fr.recordAudit(newAuditLogEntry(models.AuditEditInvite, c.Actor, c.CourseID, invite.Email,
+		inviteEditDiff(invite, c.Permission, expiresAt)))
+	return nil
+}
+
+func (fr *FirebaseRepository) RevokeInvite(c *models.RevokeInviteRequest) error {
+	invite, err := fr.getCourseInvite(c.CourseID, c.InviteID)
+	if err != nil {
+		return err
+	}
+
+	_, err = fr.firestoreClient.Collection(models.FirestoreInvitesCollection).Doc(c.InviteID).Delete(firebase.Context)
+	if err != nil {
+		return err
+	}
+
+	fr.recordAudit(newAuditLogEntry(models.AuditRevokeInvite, c.Actor, c.CourseID, invite.Email, map[string]models.AuditChange{
+		"invite": {Old: invite.Permission},
+	}))
+	return nil
+}
+
+// getCourseInvite gets an invite, returning qerrors.InviteNotFoundError if it is not an invite to the given course.
+func (fr *FirebaseRepository) getCourseInvite(courseID string, inviteID string) (*models.CourseInvite, error) {
+	doc, err := fr.firestoreClient.Collection(models.FirestoreInvitesCollection).Doc(inviteID).Get(firebase.Context)
+	if status.Code(err) == codes.NotFound {
+		return nil, qerrors.InviteNotFoundError
+	} else if err != nil {
+		return nil, err
+	}
+
+	invite, err := decodeInvite(doc)
+	if err != nil {
+		return nil, err
+	}
+	if invite.CourseID != courseID {
+		return nil, qerrors.InviteNotFoundError
+	}
+	return invite, nil
+}
+
+// inviteToCourse records an invite for an email that does not belong to a user yet. An existing invite of the email
+// to the same course is updated rather than duplicated.
+func (fr *FirebaseRepository) inviteToCourse(c *models.AddCoursePermissionRequest) error {
+	email := normalizeEmail(c.Email)
+	collection := fr.firestoreClient.Collection(models.FirestoreInvitesCollection)
+
+	docs, err := collection.Where("email", "==", email).Where("courseID", "==", c.CourseID).Limit(1).
+		Documents(firebase.Context).GetAll()
+	if err != nil {
+		return err
+	}
+
+	if len(docs) > 0 {
+		invite, err := decodeInvite(docs[0])
+		if err != nil {
+			return err
+		}
+
+		_, err = docs[0].Ref.Update(firebase.Context, []firestore.Update{
+			{Path: "permission", Value: c.Permission},
+			{Path: "expiresAt", Value: c.ExpiresAt},
+		})
+		if err != nil {
+			return err
+		}
+
+		fr.recordAudit(newAuditLogEntry(models.AuditAddPermission, c.Actor, c.CourseID, email,
+			inviteEditDiff(invite, c.Permission, c.ExpiresAt)))
+		return nil
+	}
+
+	invite := newInvite(c, time.Now())
+	_, _, err = collection.Add(firebase.Context, map[string]interface{}{
+		"email":      invite.Email,
+		"courseID":   invite.CourseID,
+		"permission": invite.Permission,
+		"invitedBy":  invite.InvitedBy,
+		"createdAt":  invite.CreatedAt,
+		"expiresAt":  invite.ExpiresAt,
+	})
+	if err != nil {
+		return fmt.Errorf("error creating invite: %v", err)
+	}
+
+	fr.recordAudit(newAuditLogEntry(models.AuditAddPermission, c.Actor, c.CourseID, email, map[string]models.AuditChange{
+		"invite": {New: c.Permission},
+	}))
+	return nil
+}
+
+// redeemInvites grants a new user the permissions of their email's unexpired invites, and notifies them of each course
+// they were added to. All of the email's invites are then deleted, expired or not.
+func (fr *FirebaseRepository) redeemInvites(userID string, email string) error {
+	docs, err := fr.firestoreClient.Collection(models.FirestoreInvitesCollection).Where("email", "==", normalizeEmail(email)).
+		Documents(firebase.Context).GetAll()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, doc := range docs {
+		invite, err := decodeInvite(doc)
+		if err != nil {
+			return err
+		}
+
+		if !invite.Expired(now) {
+			err = fr.AddPermission(&models.AddCoursePermissionRequest{
+				CourseID:   invite.CourseID,
+				Email:      email,
+				Permission: invite.Permission,
+			})
+			if err != nil {
+				glog.Warningf("there was a problem adding course permission to a user: %v\n", err)
+			} else if course, err := fr.GetCourseByID(invite.CourseID); err == nil {
+				if err := fr.AddNotification(userID, inviteNotification(course, invite)); err != nil {
+					glog.Warningf("there was a problem notifying a user of an invite: %v\n", err)
+				}
+			}
+		}
+
+		// Delete the doc.
+		_, err = doc.Ref.Delete(firebase.Context)
+		if err != nil {
+			glog.Warningf("there was a problem deleting invite: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// decodeInvite destructures an invite document.
+func decodeInvite(doc *firestore.DocumentSnapshot) (*models.CourseInvite, error) {
+	var invite models.CourseInvite
+	err := mapstructure.Decode(doc.Data(), &invite)
+	if err != nil {
+		return nil, err
+	}
+
+	invite.ID = doc.Ref.ID
+	return &invite, nil
+}
+
+// decodeInvites destructures a list of invite documents.
+func decodeInvites(docs []*firestore.DocumentSnapshot) ([]*models.CourseInvite, error) {
+	invites := make([]*models.CourseInvite, 0, len(docs))
+	for _, doc := range docs {
+		invite, err := decodeInvite(doc)
+		if err != nil {
+			return nil, err
+		}
+		invites = append(invites, invite)
+	}
+	return invites, nil
+}
+
+// newInvite describes the invite that AddPermission records for an email without a user.
+func newInvite(c *models.AddCoursePermissionRequest, now time.Time) *models.CourseInvite {
+	invite := &models.CourseInvite{
+		Email:      normalizeEmail(c.Email),
+		CourseID:   c.CourseID,
+		Permission: c.Permission,
+		CreatedAt:  now,
+		ExpiresAt:  c.ExpiresAt,
+	}
+	if c.Actor != nil {
+		invite.InvitedBy = c.Actor.ID
+	}
+	return invite
+}
+
+// sortInvites sorts invites from oldest to newest.
+func sortInvites(invites []*models.CourseInvite) {
+	sort.Slice(invites, func(i, j int) bool {
+		return invites[i].CreatedAt.Before(invites[j].CreatedAt)
+	})
+}
+
+// unexpiredInvites filters out the invites that have expired by now.
+func unexpiredInvites(invites []*models.CourseInvite, now time.Time) []*models.CourseInvite {
+	unexpired := make([]*models.CourseInvite, 0, len(invites))
+	for _, invite := range invites {
+		if !invite.Expired(now) {
+			unexpired = append(unexpired, invite)
+		}
+	}
+	return unexpired
+}
+
+// inviteEditDiff returns the fields of invite that changing its permission and expiry changes.
+func inviteEditDiff(invite *models.CourseInvite, permission string, expiresAt time.Time) map[string]models.AuditChange {
+	diff := make(map[string]models.AuditChange)
+	addAuditChange(diff, "invite", invite.Permission, permission)
+	if !invite.ExpiresAt.Equal(expiresAt) {
+		diff["expiresAt"] = models.AuditChange{Old: invite.ExpiresAt, New: expiresAt}
+	}
+	return diff
+}
+
+// inviteNotification tells a user that an invite added them to course.
+func inviteNotification(course *models.Course, invite *models.CourseInvite) models.Notification {
+	return models.Notification{
+		Title:     "You have been added to " + course.Code,
+		Body:      fmt.Sprintf("You have been added to %s as %s.", course.Title, strings.ToLower(invite.Permission)),
+		Timestamp: time.Now(),
+		Type:      models.NotificationAnnouncement,
+	}
+}
+
+// normalizeEmail puts an email in the form that invites are stored in, so that differences in case do not stop them
+// from being found.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"signmeup/internal/models"
+	"signmeup/internal/qerrors"
+	"sort"
+	"strings"
+)
+
+const (
+	// defaultCoursePageSize is the number of courses returned when a listing does not give a limit, and
+	// maxCoursePageSize is the most that can be requested at once.
+	defaultCoursePageSize = 50
+	maxCoursePageSize     = 200
+)
+
+// listCourses filters, sorts and pages courses as described by a ListCoursesRequest.
+func listCourses(courses []*models.Course, c *models.ListCoursesRequest) (*models.CoursePage, error) {
+	less, err := courseSortLess(c.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	var courseIDs map[string]bool
+	if c.CourseIDs != nil {
+		courseIDs = make(map[string]bool, len(c.CourseIDs))
+		for _, courseID := range c.CourseIDs {
+			courseIDs[courseID] = true
+		}
+	}
+
+	prefix := strings.ToLower(c.CodePrefix)
+	matches := make([]*models.Course, 0, len(courses))
+	for _, course := range courses {
+		if (c.Term != "" && course.Term != c.Term) ||
+			(c.Archived != nil && course.IsArchived != *c.Archived) ||
+			(courseIDs != nil && !courseIDs[course.ID]) ||
+			!strings.HasPrefix(strings.ToLower(course.Code), prefix) ||
+			(c.Viewer != nil && course.RestrictToEnrolled && !course.IsEnrolled(c.Viewer)) {
+			continue
+		}
+		matches = append(matches, course)
+	}
+
+	before := func(a, b *models.Course) bool {
+		if c.Descending {
+			return less(b, a)
+		}
+		return less(a, b)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return before(matches[i], matches[j])
+	})
+
+	// Start at the first course after the cursor if there is one.
+	start := 0
+	if c.Cursor != "" {
+		cursor, err := decodeCourseCursor(c.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		start = sort.Search(len(matches), func(i int) bool {
+			return before(cursor, matches[i])
+		})
+	}
+
+	end := start + coursePageSize(c.Limit)
+	if end > len(matches) {
+		end = len(matches)
+	}
+	page := &models.CoursePage{Courses: matches[start:end]}
+	if end < len(matches) {
+		page.NextCursor = encodeCourseCursor(matches[end-1])
+	}
+	return page, nil
+}
+
+// courseCursor is the position of a course in a listing: the fields that courses are sorted by.
+type courseCursor struct {
+	Code  string `json:"c"`
+	Title string `json:"t"`
+	Term  string `json:"r"`
+	ID    string `json:"i"`
+}
+
+// encodeCourseCursor returns a cursor for the courses listed after course.
+func encodeCourseCursor(course *models.Course) string {
+	// Marshalling a struct of strings cannot fail.
+	data, _ := json.Marshal(courseCursor{Code: course.Code, Title: course.Title, Term: course.Term, ID: course.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCourseCursor returns a course at the position of a cursor, which can be compared with the courses of a listing.
+func decodeCourseCursor(cursor string) (*models.Course, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, qerrors.InvalidCourseCursorError
+	}
+	var cc courseCursor
+	if err := json.Unmarshal(data, &cc); err != nil || cc.ID == "" {
+		return nil, qerrors.InvalidCourseCursorError
+	}
+	return &models.Course{Code: cc.Code, Title: cc.Title, Term: cc.Term, ID: cc.ID}, nil
+}
+
+// sortCoursesByCode sorts courses by code.
+func sortCoursesByCode(courses []*models.Course) {
+	sort.Slice(courses, func(i, j int) bool {
+		return courses[i].Code < courses[j].Code
+	})
+}
+
+// courseSortLess returns the ordering of courses for a sort, which is by code if the sort is empty.
+func courseSortLess(by models.CourseSort) (func(a, b *models.Course) bool, error) {
+	// byCode ignores case, as code prefixes do, and breaks ties between courses with the same code in different terms
+	// by ID, so that the order is total.
+	byCode := func(a, b *models.Course) bool {
+		if codeA, codeB := strings.ToLower(a.Code), strings.ToLower(b.Code); codeA != codeB {
+			return codeA < codeB
+		}
+		return a.ID < b.ID
+	}
+
+	switch by {
+	case "", models.CourseSortCode:
+		return byCode, nil
+	case models.CourseSortTitle:
+		return func(a, b *models.Course) bool {
+			if titleA, titleB := strings.ToLower(a.Title), strings.ToLower(b.Title); titleA != titleB {
+				return titleA < titleB
+			}
+			return byCode(a, b)
+		}, nil
+	case models.CourseSortTerm:
+		return func(a, b *models.Course) bool {
+			if a.Term != b.Term {
+				return a.Term < b.Term
+			}
+			return byCode(a, b)
+		}, nil
+	default:
+		return nil, qerrors.InvalidCourseSortError
+	}
+}
+
+// coursePageSize returns the number of courses to list for a requested limit.
+func coursePageSize(limit int) int {
+	if limit <= 0 {
+		return defaultCoursePageSize
+	}
+	if limit > maxCoursePageSize {
+		return maxCoursePageSize
+	}
+	return limit
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"testing"
+
+	"signmeup/internal/models"
+	"signmeup/internal/qerrors"
+)
+
+func TestListCoursesResumesAfterChangedCursorCourse(t *testing.T) {
+	courses := []*models.Course{
+		{ID: "a", Code: "cs0150", Title: "Intro", Term: "fall"},
+		{ID: "b", Code: "cs0170", Title: "Fundamentals", Term: "fall"},
+		{ID: "c", Code: "cs0200", Title: "Program Design", Term: "fall"},
+	}
+	notArchived := false
+
+	for _, descending := range []bool{false, true} {
+		req := &models.ListCoursesRequest{CodePrefix: "cs", Archived: &notArchived, Descending: descending, Limit: 1}
+		page, err := listCourses(courses, req)
+		if err != nil {
+			t.Fatalf("listing first page: %v", err)
+		}
+		first := page.Courses[0]
+
+		// The first page's course is archived and renamed out of the prefix before the next page is requested.
+		changed := make([]*models.Course, 0, len(courses))
+		for _, course := range courses {
+			if course.ID == first.ID {
+				course = &models.Course{ID: course.ID, Code: "eng0100", Title: course.Title, Term: course.Term, IsArchived: true}
+			}
+			changed = append(changed, course)
+		}
+
+		req.Cursor = page.NextCursor
+		page, err = listCourses(changed, req)
+		if err != nil {
+			t.Fatalf("listing next page (descending %v): %v", descending, err)
+		}
+		want := "b"
+		if got := page.Courses[0].ID; got != want {
+			t.Errorf("next page (descending %v) starts with %s, want %s", descending, got, want)
+		}
+	}
+}
+
+func TestListCoursesRejectsInvalidCursor(t *testing.T) {
+	_, err := listCourses(nil, &models.ListCoursesRequest{Cursor: "not a cursor"})
+	if err != qerrors.InvalidCourseCursorError {
+		t.Errorf("got error %v, want %v", err, qerrors.InvalidCourseCursorError)
+	}
+}
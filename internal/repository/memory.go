@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"sync"
+
+	"signmeup/internal/models"
+
+	"github.com/google/uuid"
+)
+
+var _ Repository = (*MemoryRepository)(nil)
+
+// MemoryRepository is a Repository that keeps all of its data in memory. It mirrors the semantics of
+// FirebaseRepository without talking to any Google services, which makes it suitable for tests and local development.
+type MemoryRepository struct {
+	lock *sync.RWMutex
+
+	courses map[string]*models.Course
+	// Map from course ID to a map from schedule ID to schedule.
+	schedules map[string]map[string]*models.Schedule
+	// Map from course ID to a map from template ID to queue template.
+	templates map[string]map[string]*models.QueueTemplate
+	queues    map[string]*models.Queue
+	// Map from queue ID to a map from ticket ID to ticket.
+	tickets map[string]map[string]*models.Ticket
+	// Map from queue ID to a map from ticket ID to the ticket's history, oldest first.
+	events map[string]map[string][]*models.TicketEvent
+	// Map from queue ID to the queue's shuffles, oldest first.
+	shuffles map[string][]*models.ShuffleRecord
+	users    map[string]*models.User
+	invites  map[string]*models.CourseInvite
+	// The audit log, oldest first.
+	auditLogs []*models.AuditLogEntry
+
+	// Map from queue ID to the channels of the queue's watchers, each with a channel that is closed to stop the watcher.
+	watchers map[string]map[chan *models.QueueSnapshot]chan struct{}
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		lock:      &sync.RWMutex{},
+		courses:   make(map[string]*models.Course),
+		schedules: make(map[string]map[string]*models.Schedule),
+		templates: make(map[string]map[string]*models.QueueTemplate),
+		queues:    make(map[string]*models.Queue),
+		tickets:   make(map[string]map[string]*models.Ticket),
+		events:    make(map[string]map[string][]*models.TicketEvent),
+		shuffles:  make(map[string][]*models.ShuffleRecord),
+		users:     make(map[string]*models.User),
+		invites:   make(map[string]*models.CourseInvite),
+
+		watchers: make(map[string]map[chan *models.QueueSnapshot]chan struct{}),
+	}
+}
+
+// Helpers
+
+func newMemoryID() string {
+	return uuid.New().String()
+}
+
+// The copy helpers below ensure that callers never share memory with the repository's internal state, much like
+// reading a document from Firestore always returns a fresh value.
+
+func copyCourse(c *models.Course) *models.Course {
+	course := *c
+	course.CoursePermissions = make(map[string]models.CoursePermission, len(c.CoursePermissions))
+	for k, v := range c.CoursePermissions {
+		course.CoursePermissions[k] = v
+	}
+	return &course
+}
+
+func copyQueue(q *models.Queue) *models.Queue {
+	queue := *q
+	if q.Course != nil {
+		course := *q.Course
+		queue.Course = &course
+	}
+	queue.PendingTickets = append([]string{}, q.PendingTickets...)
+	queue.CompletedTickets = append([]string{}, q.CompletedTickets...)
+	queue.Categories = append([]string(nil), q.Categories...)
+	return &queue
+}
+
+func copySchedule(s *models.Schedule) *models.Schedule {
+	schedule := *s
+	schedule.Staff = append([]string(nil), s.Staff...)
+	schedule.ExceptionDates = append([]string(nil), s.ExceptionDates...)
+	return &schedule
+}
+
+func copyQueueTemplate(t *models.QueueTemplate) *models.QueueTemplate {
+	template := *t
+	template.Categories = append([]string(nil), t.Categories...)
+	return &template
+}
+
+func copyTicket(t *models.Ticket) *models.Ticket {
+	ticket := *t
+	return &ticket
+}
+
+func copyShuffleRecord(r *models.ShuffleRecord) *models.ShuffleRecord {
+	record := *r
+	record.Before = append([]string{}, r.Before...)
+	record.After = append([]string{}, r.After...)
+	return &record
+}
+
+func copyUser(u *models.User) *models.User {
+	user := *u
+	profile := *u.Profile
+	profile.CoursePermissions = make(map[string]models.CoursePermission, len(u.CoursePermissions))
+	for k, v := range u.CoursePermissions {
+		profile.CoursePermissions[k] = v
+	}
+	profile.Notifications = append([]models.Notification{}, u.Notifications...)
+	profile.FavoriteCourses = append([]string{}, u.FavoriteCourses...)
+	user.Profile = &profile
+	return &user
+}
+
+// removeString returns s without any occurrences of str.
+func removeString(s []string, str string) []string {
+	res := make([]string, 0, len(s))
+	for _, v := range s {
+		if v != str {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// appendUnique appends str to s if it is not already present, mirroring firestore.ArrayUnion.
+func appendUnique(s []string, str string) []string {
+	if contains(s, str) {
+		return s
+	}
+	return append(s, str)
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"signmeup/internal/models"
+	"signmeup/internal/qerrors"
+	"time"
+)
+
+func (mr *MemoryRepository) AddAuditLog(entry *models.AuditLogEntry) error {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	mr.addAuditLog(entry)
+	return nil
+}
+
+// addAuditLog records an entry in the audit log, setting its ID and timestamp. The caller must hold the write lock.
+func (mr *MemoryRepository) addAuditLog(entry *models.AuditLogEntry) {
+	entry.ID = newMemoryID()
+	entry.Timestamp = time.Now()
+	mr.auditLogs = append(mr.auditLogs, copyAuditLogEntry(entry))
+}
+
+func (mr *MemoryRepository) GetAuditLogs(c *models.GetAuditLogsRequest) (*models.AuditLogPage, error) {
+	mr.lock.RLock()
+	defer mr.lock.RUnlock()
+
+	// Walk the log newest first, starting after the cursor if there is one.
+	start := len(mr.auditLogs) - 1
+	if c.Cursor != "" {
+		found := false
+		for i, entry := range mr.auditLogs {
+			if entry.ID == c.Cursor {
+				start, found = i-1, true
+				break
+			}
+		}
+		if !found {
+			return nil, qerrors.AuditCursorNotFoundError
+		}
+	}
+
+	limit := auditPageSize(c.Limit)
+	entries := make([]*models.AuditLogEntry, 0)
+	for i := start; i >= 0 && len(entries) < limit; i-- {
+		entry := mr.auditLogs[i]
+		if (c.CourseID != "" && entry.CourseID != c.CourseID) ||
+			(c.ActorID != "" && entry.ActorID != c.ActorID) ||
+			(c.Action != "" && entry.Action != c.Action) {
+			continue
+		}
+		entries = append(entries, copyAuditLogEntry(entry))
+	}
+	return newAuditLogPage(entries, limit), nil
+}
+
+func copyAuditLogEntry(e *models.AuditLogEntry) *models.AuditLogEntry {
+	entry := *e
+	entry.Diff = make(map[string]models.AuditChange, len(e.Diff))
+	for k, v := range e.Diff {
+		entry.Diff[k] = v
+	}
+	return &entry
+}
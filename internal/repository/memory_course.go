@@ -0,0 +1,269 @@
+package repository
+
+import (
+	"signmeup/internal/models"
+	"signmeup/internal/qerrors"
+)
+
+func (mr *MemoryRepository) GetCourseByID(ID string) (*models.Course, error) {
+	mr.lock.RLock()
+	defer mr.lock.RUnlock()
+
+	course, ok := mr.courses[ID]
+	if !ok {
+		return nil, qerrors.CourseNotFoundError
+	}
+	return copyCourse(course), nil
+}
+
+func (mr *MemoryRepository) GetCourseByInfo(code string, term string) (*models.Course, error) {
+	mr.lock.RLock()
+	defer mr.lock.RUnlock()
+
+	for _, course := range mr.courses {
+		if course.Code == code && course.Term == term {
+			return copyCourse(course), nil
+		}
+	}
+	return nil, qerrors.CourseNotFoundError
+}
+
+func (mr *MemoryRepository) CreateCourse(c *models.CreateCourseRequest) (*models.Course, error) {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	course := &models.Course{
+		ID:                 newMemoryID(),
+		Title:              c.Title,
+		Code:               c.Code,
+		Term:               c.Term,
+		IsArchived:         false,
+		CoursePermissions:  map[string]models.CoursePermission{},
+		RestrictToEnrolled: c.RestrictToEnrolled,
+	}
+	mr.courses[course.ID] = course
+
+	return copyCourse(course), nil
+}
+
+func (mr *MemoryRepository) DeleteCourse(c *models.DeleteCourseRequest) error {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	course, ok := mr.courses[c.CourseID]
+	if !ok {
+		return qerrors.CourseNotFoundError
+	}
+
+	// Delete this course from all users with permissions.
+	for userID := range course.CoursePermissions {
+		if user, ok := mr.users[userID]; ok {
+			delete(user.CoursePermissions, course.ID)
+		}
+	}
+
+	delete(mr.courses, c.CourseID)
+	delete(mr.schedules, c.CourseID)
+	delete(mr.templates, c.CourseID)
+
+	mr.addAuditLog(newAuditLogEntry(models.AuditDeleteCourse, c.Actor, course.ID, course.ID, courseDeleteDiff(course)))
+	return nil
+}
+
+func (mr *MemoryRepository) EditCourse(c *models.EditCourseRequest) error {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	course, ok := mr.courses[c.CourseID]
+	if !ok {
+		return qerrors.CourseNotFoundError
+	}
+
+	diff := courseEditDiff(course, c)
+	course.Title = c.Title
+	course.Term = c.Term
+	course.Code = c.Code
+	if c.RestrictToEnrolled != nil {
+		course.RestrictToEnrolled = *c.RestrictToEnrolled
+	}
+
+	mr.addAuditLog(newAuditLogEntry(models.AuditEditCourse, c.Actor, course.ID, course.ID, diff))
+	return nil
+}
+
+func (mr *MemoryRepository) AddPermission(c *models.AddCoursePermissionRequest) error {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	return mr.addPermission(c)
+}
+
+// addPermission grants a course permission to the user with the given email, or records an invite if no such user
+// exists yet. The caller must hold the write lock.
+func (mr *MemoryRepository) addPermission(c *models.AddCoursePermissionRequest) error {
+	if err := validCoursePermission(c.Permission); err != nil {
+		return err
+	}
+
+	user := mr.userByEmail(c.Email)
+	if user == nil {
+		// The user doesn't exist; invite them instead.
+		mr.inviteToCourse(c)
+		return nil
+	}
+
+	course, ok := mr.courses[c.CourseID]
+	if !ok {
+		return qerrors.CourseNotFoundError
+	}
+
+	diff := make(map[string]models.AuditChange)
+	addAuditChange(diff, "permission", coursePermission(course, user.ID), c.Permission)
+	course.CoursePermissions[user.ID] = models.CoursePermission(c.Permission)
+	user.CoursePermissions[c.CourseID] = models.CoursePermission(c.Permission)
+
+	mr.addAuditLog(newAuditLogEntry(models.AuditAddPermission, c.Actor, c.CourseID, normalizeEmail(c.Email), diff))
+	return nil
+}
+
+func (mr *MemoryRepository) RemovePermission(c *models.RemoveCoursePermissionRequest) error {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	course, ok := mr.courses[c.CourseID]
+	if !ok {
+		return qerrors.CourseNotFoundError
+	}
+	user, ok := mr.users[c.UserID]
+	if !ok {
+		return qerrors.UserNotFoundError
+	}
+
+	diff := map[string]models.AuditChange{
+		"permission": {Old: coursePermission(course, c.UserID)},
+	}
+	delete(course.CoursePermissions, c.UserID)
+	delete(user.CoursePermissions, c.CourseID)
+
+	// The removal is audited under the user's email, as AddPermission is, so that the two can be matched.
+	mr.addAuditLog(newAuditLogEntry(models.AuditRemovePermission, c.Actor, c.CourseID, normalizeEmail(user.Email), diff))
+	return nil
+}
+
+func (mr *MemoryRepository) BulkUpload(c *models.BulkUploadRequest) (*models.BulkUploadReport, error) {
+	return bulkUpload(mr, c)
+}
+
+func (mr *MemoryRepository) ImportRoster(c *models.ImportRosterRequest) (*models.RosterImportResult, error) {
+	return importRoster(mr, c)
+}
+
+func (mr *MemoryRepository) GetCoursesByTerm(term string) ([]*models.Course, error) {
+	mr.lock.RLock()
+	defer mr.lock.RUnlock()
+
+	courses := make([]*models.Course, 0)
+	for _, course := range mr.courses {
+		if course.Term == term {
+			courses = append(courses, copyCourse(course))
+		}
+	}
+	sortCoursesByCode(courses)
+	return courses, nil
+}
+
+func (mr *MemoryRepository) ListCourses(c *models.ListCoursesRequest) (*models.CoursePage, error) {
+	mr.lock.RLock()
+	defer mr.lock.RUnlock()
+
+	courses := make([]*models.Course, 0, len(mr.courses))
+	for _, course := range mr.courses {
+		courses = append(courses, copyCourse(course))
+	}
+	return listCourses(courses, c)
+}
+
+func (mr *MemoryRepository) SetCourseArchived(c *models.SetCourseArchivedRequest) error {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	course, ok := mr.courses[c.CourseID]
+	if !ok {
+		return qerrors.CourseNotFoundError
+	}
+	if course.IsArchived == c.Archived {
+		return nil
+	}
+
+	mr.addAuditLog(newAuditLogEntry(courseArchiveAction(c.Archived), c.Actor, course.ID, course.ID, map[string]models.AuditChange{
+		"isArchived": {Old: course.IsArchived, New: c.Archived},
+	}))
+	course.IsArchived = c.Archived
+	return nil
+}
+
+func (mr *MemoryRepository) RolloverTerm(c *models.RolloverTermRequest) (*models.RolloverReport, error) {
+	return rolloverTerm(mr, c)
+}
+
+func (mr *MemoryRepository) ArchiveCoursesByTerm(c *models.ArchiveCoursesByTermRequest) (int, error) {
+	return archiveCoursesByTerm(mr, c)
+}
+
+// DeleteCoursesByTerm deletes all courses within the given term.
+func (mr *MemoryRepository) DeleteCoursesByTerm(term string) error {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	for id, course := range mr.courses {
+		if course.Term == term {
+			delete(mr.courses, id)
+			delete(mr.schedules, id)
+			delete(mr.templates, id)
+		}
+	}
+	return nil
+}
+
+func (mr *MemoryRepository) GetCourseAnalytics(c *models.GetCourseAnalyticsRequest) (*models.CourseAnalytics, error) {
+	mr.lock.RLock()
+	defer mr.lock.RUnlock()
+
+	if _, ok := mr.courses[c.CourseID]; !ok {
+		return nil, qerrors.CourseNotFoundError
+	}
+
+	var tickets []*models.Ticket
+	for _, queue := range mr.queues {
+		if queue.CourseID != c.CourseID {
+			continue
+		}
+		for _, t := range mr.tickets[queue.ID] {
+			if !t.CreatedAt.Before(c.From) && t.CreatedAt.Before(c.To) {
+				tickets = append(tickets, t)
+			}
+		}
+	}
+
+	return courseAnalytics(c, tickets), nil
+}
+
+func (mr *MemoryRepository) GetStudentVisits(courseID string, userID string) ([]*models.StudentVisit, error) {
+	mr.lock.RLock()
+	defer mr.lock.RUnlock()
+
+	visits := make([]*models.StudentVisit, 0)
+	for _, queue := range mr.queues {
+		if queue.CourseID != courseID {
+			continue
+		}
+		for _, t := range mr.tickets[queue.ID] {
+			if t.User.UserID == userID {
+				visits = append(visits, newStudentVisit(queue, t, mr.users[t.ClaimedBy]))
+			}
+		}
+	}
+
+	sortStudentVisits(visits)
+	return visits, nil
+}
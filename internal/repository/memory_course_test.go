@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"testing"
+
+	"signmeup/internal/models"
+)
+
+func TestPermissionAuditTargetsMatch(t *testing.T) {
+	repo, queue, student, staff := newTestQueue(t)
+	err := repo.AddPermission(&models.AddCoursePermissionRequest{
+		CourseID:   queue.CourseID,
+		Email:      student.Email,
+		Permission: string(models.CourseStaff),
+		Actor:      staff,
+	})
+	if err != nil {
+		t.Fatalf("adding permission: %v", err)
+	}
+	err = repo.RemovePermission(&models.RemoveCoursePermissionRequest{CourseID: queue.CourseID, UserID: student.ID, Actor: staff})
+	if err != nil {
+		t.Fatalf("removing permission: %v", err)
+	}
+
+	targets := make(map[models.AuditAction]string)
+	for _, action := range []models.AuditAction{models.AuditAddPermission, models.AuditRemovePermission} {
+		page, err := repo.GetAuditLogs(&models.GetAuditLogsRequest{CourseID: queue.CourseID, ActorID: staff.ID, Action: action})
+		if err != nil {
+			t.Fatalf("getting %s audit logs: %v", action, err)
+		}
+		if len(page.Entries) != 1 {
+			t.Fatalf("got %d %s audit log entries, want 1", len(page.Entries), action)
+		}
+		targets[action] = page.Entries[0].Target
+	}
+	if targets[models.AuditAddPermission] != targets[models.AuditRemovePermission] {
+		t.Errorf("permission was added to %q but removed from %q", targets[models.AuditAddPermission],
+			targets[models.AuditRemovePermission])
+	}
+}
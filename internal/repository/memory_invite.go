@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"signmeup/internal/models"
+	"signmeup/internal/qerrors"
+	"time"
+)
+
+func (mr *MemoryRepository) GetCourseInvites(courseID string) ([]*models.CourseInvite, error) {
+	mr.lock.RLock()
+	defer mr.lock.RUnlock()
+
+	invites := make([]*models.CourseInvite, 0)
+	for _, invite := range mr.invites {
+		if invite.CourseID == courseID {
+			i := *invite
+			invites = append(invites, &i)
+		}
+	}
+	sortInvites(invites)
+	return invites, nil
+}
+
+func (mr *MemoryRepository) GetInvitesByEmail(email string) ([]*models.CourseInvite, error) {
+	mr.lock.RLock()
+	defer mr.lock.RUnlock()
+
+	invites := make([]*models.CourseInvite, 0)
+	for _, invite := range mr.invites {
+		if invite.Email == normalizeEmail(email) {
+			i := *invite
+			invites = append(invites, &i)
+		}
+	}
+	sortInvites(invites)
+	return unexpiredInvites(invites, time.Now()), nil
+}
+
+func (mr *MemoryRepository) EditInvite(c *models.EditInviteRequest) error {
+	if err := validCoursePermission(c.Permission); err != nil {
+		return err
+	}
+
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	invite, ok := mr.invites[c.InviteID]
+	if !ok || invite.CourseID != c.CourseID {
+		return qerrors.InviteNotFoundError
+	}
+
+	expiresAt := invite.ExpiresAt
+	if c.ExpiresAt != nil {
+		expiresAt = *c.ExpiresAt
+	}
+
+	diff := inviteEditDiff(invite, c.Permission, expiresAt)
+	invite.Permission = c.Permission
+	invite.ExpiresAt = expiresAt
+
+	mr.addAuditLog(newAuditLogEntry(models.AuditEditInvite, c.Actor, c.CourseID, invite.Email, diff))
+	return nil
+}
+
+func (mr *MemoryRepository) RevokeInvite(c *models.RevokeInviteRequest) error {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	invite, ok := mr.invites[c.InviteID]
+	if !ok || invite.CourseID != c.CourseID {
+		return qerrors.InviteNotFoundError
+	}
+	delete(mr.invites, c.InviteID)
+
+	mr.addAuditLog(newAuditLogEntry(models.AuditRevokeInvite, c.Actor, c.CourseID, invite.Email, map[string]models.AuditChange{
+		"invite": {Old: invite.Permission},
+	}))
+	return nil
+}
+
+// inviteToCourse records an invite for an email that does not belong to a user yet. An existing invite of the email
+// to the same course is updated rather than duplicated. The caller must hold the write lock.
+func (mr *MemoryRepository) inviteToCourse(c *models.AddCoursePermissionRequest) {
+	email := normalizeEmail(c.Email)
+	for _, invite := range mr.invites {
+		if invite.Email == email && invite.CourseID == c.CourseID {
+			diff := inviteEditDiff(invite, c.Permission, c.ExpiresAt)
+			invite.Permission = c.Permission
+			invite.ExpiresAt = c.ExpiresAt
+
+			mr.addAuditLog(newAuditLogEntry(models.AuditAddPermission, c.Actor, c.CourseID, email, diff))
+			return
+		}
+	}
+
+	invite := newInvite(c, time.Now())
+	invite.ID = newMemoryID()
+	mr.invites[invite.ID] = invite
+
+	mr.addAuditLog(newAuditLogEntry(models.AuditAddPermission, c.Actor, c.CourseID, email, map[string]models.AuditChange{
+		"invite": {New: c.Permission},
+	}))
+}
+
+// redeemInvites grants a new user the permissions of their email's unexpired invites, and notifies them of each course
+// they were added to. All of the email's invites are then deleted, expired or not. The caller must hold the write lock.
+func (mr *MemoryRepository) redeemInvites(user *models.User) {
+	now := time.Now()
+	for id, invite := range mr.invites {
+		if invite.Email != normalizeEmail(user.Email) {
+			continue
+		}
+
+		if !invite.Expired(now) {
+			err := mr.addPermission(&models.AddCoursePermissionRequest{
+				CourseID:   invite.CourseID,
+				Email:      user.Email,
+				Permission: invite.Permission,
+			})
+			if err == nil {
+				_ = mr.addNotification(user.ID, inviteNotification(mr.courses[invite.CourseID], invite))
+			}
+		}
+		delete(mr.invites, id)
+	}
+}
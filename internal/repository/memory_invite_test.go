@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"signmeup/internal/models"
+)
+
+func TestEditInviteKeepsOmittedExpiry(t *testing.T) {
+	repo, queue, _, staff := newTestQueue(t)
+	expiresAt := time.Now().Add(time.Hour).Round(0)
+	err := repo.AddPermission(&models.AddCoursePermissionRequest{
+		CourseID:   queue.CourseID,
+		Email:      "invited@brown.edu",
+		Permission: string(models.CourseStaff),
+		ExpiresAt:  expiresAt,
+	})
+	if err != nil {
+		t.Fatalf("inviting: %v", err)
+	}
+	invites, err := repo.GetCourseInvites(queue.CourseID)
+	if err != nil || len(invites) != 1 {
+		t.Fatalf("got invites %v and error %v, want one invite", invites, err)
+	}
+
+	err = repo.EditInvite(&models.EditInviteRequest{
+		CourseID:   queue.CourseID,
+		InviteID:   invites[0].ID,
+		Permission: string(models.CourseAdmin),
+		Actor:      staff,
+	})
+	if err != nil {
+		t.Fatalf("editing invite: %v", err)
+	}
+
+	invites, err = repo.GetCourseInvites(queue.CourseID)
+	if err != nil {
+		t.Fatalf("getting invites: %v", err)
+	}
+	if got := invites[0]; got.Permission != string(models.CourseAdmin) || !got.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("got invite as %s expiring at %v, want %s expiring at %v",
+			got.Permission, got.ExpiresAt, models.CourseAdmin, expiresAt)
+	}
+}
@@ -0,0 +1,574 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"signmeup/internal/models"
+	"signmeup/internal/qerrors"
+
+	"github.com/golang/glog"
+)
+
+func (mr *MemoryRepository) CreateQueue(c *models.CreateQueueRequest) (*models.Queue, error) {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	return mr.createQueue(c)
+}
+
+// createQueue creates a queue. The caller must hold the write lock.
+func (mr *MemoryRepository) createQueue(c *models.CreateQueueRequest) (*models.Queue, error) {
+	queueCourse, ok := mr.courses[c.CourseID]
+	if !ok {
+		return nil, qerrors.CourseNotFoundError
+	}
+	if err := checkNotArchived(queueCourse); err != nil {
+		return nil, err
+	}
+	if err := validOrderingPolicy(c.OrderingPolicy); err != nil {
+		return nil, err
+	}
+	if err := validCategories(c.Categories); err != nil {
+		return nil, err
+	}
+
+	queue := &models.Queue{
+		ID:                 newMemoryID(),
+		Title:              c.Title,
+		Description:        c.Description,
+		Location:           c.Location,
+		EndTime:            c.EndTime,
+		CourseID:           queueCourse.ID,
+		AllowTicketEditing: c.AllowTicketEditing,
+		ShowMeetingLinks:   c.ShowMeetingLinks,
+		// Like the Firestore document, the queue only keeps a summary of its course.
+		Course: &models.Course{
+			ID:    queueCourse.ID,
+			Title: queueCourse.Title,
+			Code:  queueCourse.Code,
+		},
+		IsCutOff:         false,
+		PendingTickets:   []string{},
+		CompletedTickets: []string{},
+		FaceMaskPolicy:   c.FaceMaskPolicy,
+		RejoinCooldown:   c.RejoinCooldown,
+		OrderingPolicy:   c.OrderingPolicy,
+		Categories:       append([]string(nil), c.Categories...),
+	}
+	mr.queues[queue.ID] = queue
+	mr.tickets[queue.ID] = make(map[string]*models.Ticket)
+	mr.events[queue.ID] = make(map[string][]*models.TicketEvent)
+
+	return copyQueue(queue), nil
+}
+
+func (mr *MemoryRepository) EditQueue(c *models.EditQueueRequest) error {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	queue, ok := mr.queues[c.QueueID]
+	if !ok {
+		return qerrors.QueueNotFoundError
+	}
+	if err := validOrderingPolicy(c.OrderingPolicy); err != nil {
+		return err
+	}
+	if err := validCategories(c.Categories); err != nil {
+		return err
+	}
+
+	queue.Title = c.Title
+	queue.Description = c.Description
+	queue.EndTime = c.EndTime
+	queue.Location = c.Location
+	queue.IsCutOff = c.IsCutOff
+	queue.ShowMeetingLinks = c.ShowMeetingLinks
+	queue.AllowTicketEditing = c.AllowTicketEditing
+	queue.FaceMaskPolicy = c.FaceMaskPolicy
+	queue.RejoinCooldown = c.RejoinCooldown
+	queue.OrderingPolicy = c.OrderingPolicy
+	queue.Categories = append([]string(nil), c.Categories...)
+	mr.publish(c.QueueID)
+	return nil
+}
+
+func (mr *MemoryRepository) DeleteQueue(c *models.DeleteQueueRequest) error {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	delete(mr.queues, c.QueueID)
+	delete(mr.tickets, c.QueueID)
+	delete(mr.events, c.QueueID)
+	delete(mr.shuffles, c.QueueID)
+
+	// Close the streams of anyone watching the queue, and stop their watchers.
+	for ch, stop := range mr.watchers[c.QueueID] {
+		close(ch)
+		close(stop)
+	}
+	delete(mr.watchers, c.QueueID)
+	return nil
+}
+
+func (mr *MemoryRepository) CutoffQueue(c *models.CutoffQueueRequest) error {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	queue, ok := mr.queues[c.QueueID]
+	if !ok {
+		return qerrors.QueueNotFoundError
+	}
+	if !c.EndedBy.IsZero() && !hasEnded(queue, c.EndedBy) {
+		return qerrors.QueueNotEndedError
+	}
+
+	queue.IsCutOff = c.IsCutOff
+	mr.publish(c.QueueID)
+	return nil
+}
+
+func (mr *MemoryRepository) GetEndedQueues(now time.Time) ([]*models.Queue, error) {
+	mr.lock.RLock()
+	defer mr.lock.RUnlock()
+
+	var queues []*models.Queue
+	for _, queue := range mr.queues {
+		if hasEnded(queue, now) {
+			queues = append(queues, copyQueue(queue))
+		}
+	}
+	return queues, nil
+}
+
+func (mr *MemoryRepository) GetCourseQueues(courseID string) ([]*models.Queue, error) {
+	mr.lock.RLock()
+	defer mr.lock.RUnlock()
+
+	queues := make([]*models.Queue, 0)
+	for _, queue := range mr.queues {
+		if queue.CourseID == courseID {
+			queues = append(queues, copyQueue(queue))
+		}
+	}
+	return queues, nil
+}
+
+func (mr *MemoryRepository) ShuffleQueue(c *models.ShuffleQueueRequest) (*models.ShuffleRecord, error) {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	queue, ok := mr.queues[c.QueueID]
+	if !ok {
+		return nil, qerrors.InvalidQueueError
+	}
+
+	seed := newShuffleSeed()
+	record := &models.ShuffleRecord{
+		ID:         newMemoryID(),
+		QueueID:    c.QueueID,
+		Seed:       seed,
+		Before:     append([]string{}, queue.PendingTickets...),
+		After:      shuffleTickets(queue.PendingTickets, seed),
+		ShuffledBy: c.ShuffledBy.ID,
+		ShuffledAt: time.Now(),
+	}
+	queue.PendingTickets = append([]string{}, record.After...)
+	mr.shuffles[c.QueueID] = append(mr.shuffles[c.QueueID], record)
+	mr.publish(c.QueueID)
+
+	return copyShuffleRecord(record), nil
+}
+
+// GetShuffleRecords returns the shuffles of a queue, most recent first.
+func (mr *MemoryRepository) GetShuffleRecords(queueID string) ([]*models.ShuffleRecord, error) {
+	mr.lock.RLock()
+	defer mr.lock.RUnlock()
+
+	records := make([]*models.ShuffleRecord, 0, len(mr.shuffles[queueID]))
+	for i := len(mr.shuffles[queueID]) - 1; i >= 0; i-- {
+		records = append(records, copyShuffleRecord(mr.shuffles[queueID][i]))
+	}
+	return records, nil
+}
+
+// CreateTicket adds a ticket to a queue, at the position given by the queue's ordering policy. The duplicate and
+// cooldown checks and the ticket's creation happen under the repository's lock, so concurrent requests from the same
+// user cannot create more than one ticket.
+func (mr *MemoryRepository) CreateTicket(c *models.CreateTicketRequest) (*models.Ticket, error) {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	// Get the queue that this ticket belongs to.
+	queue, ok := mr.queues[c.QueueID]
+	if !ok {
+		return nil, qerrors.InvalidQueueError
+	}
+	if isClosed(queue, time.Now()) {
+		return nil, qerrors.QueueClosedError
+	}
+	course, ok := mr.courses[queue.CourseID]
+	if !ok {
+		return nil, qerrors.CourseNotFoundError
+	}
+	if err := checkNotArchived(course); err != nil {
+		return nil, err
+	}
+	if err := checkEnrollment(course, c.CreatedBy); err != nil {
+		return nil, err
+	}
+	if err := checkCategory(queue, c.Category); err != nil {
+		return nil, err
+	}
+
+	// Check that this user is not already in the queue.
+	history := make([]*models.Ticket, 0, len(mr.tickets[c.QueueID]))
+	for _, ticket := range mr.tickets[c.QueueID] {
+		if err := checkRejoin(queue, ticket, c.CreatedBy.ID); err != nil {
+			return nil, err
+		}
+		history = append(history, ticket)
+	}
+
+	ticket := &models.Ticket{
+		ID: newMemoryID(),
+		User: models.TicketUserdata{
+			UserID:      c.CreatedBy.ID,
+			Email:       c.CreatedBy.Email,
+			PhotoURL:    c.CreatedBy.PhotoURL,
+			DisplayName: c.CreatedBy.DisplayName,
+			Pronouns:    c.CreatedBy.Pronouns,
+		},
+		CreatedAt:   time.Now(),
+		Status:      models.StatusWaiting,
+		Description: c.Description,
+		Anonymize:   c.Anonymize,
+		Category:    c.Category,
+	}
+	mr.tickets[c.QueueID][ticket.ID] = ticket
+	mr.addTicketEvent(c.QueueID, newTicketEvent(ticket.ID, models.TicketCreated, c.CreatedBy, "", ticket.Status, "", ticket.Description))
+	queue.PendingTickets = insertTicket(queue, history, ticket.ID, c.CreatedBy.ID, ticket.CreatedAt)
+	mr.publish(c.QueueID)
+
+	res := copyTicket(ticket)
+	res.Queue = copyQueue(queue)
+	return res, nil
+}
+
+func (mr *MemoryRepository) ClaimNextTicket(c *models.ClaimNextTicketRequest) (*models.Ticket, error) {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	queue, ok := mr.queues[c.QueueID]
+	if !ok {
+		return nil, qerrors.InvalidQueueError
+	}
+	if c.Category != "" {
+		if err := checkCategory(queue, c.Category); err != nil {
+			return nil, err
+		}
+	}
+
+	ticket := nextClaimableTicket(mr.pendingTickets(c.QueueID), c.Category)
+	if ticket == nil {
+		return nil, qerrors.NoWaitingTicketsError
+	}
+	if err := checkTransition(ticket.Status, models.StatusClaimed, ticketActorOf(c.ClaimedBy, queue, ticket)); err != nil {
+		return nil, err
+	}
+
+	mr.addTicketEvent(c.QueueID, newTicketEvent(ticket.ID, models.TicketEdited, c.ClaimedBy, ticket.Status, models.StatusClaimed, "", ""))
+	ticket.Status = models.StatusClaimed
+	ticket.ClaimedAt = time.Now()
+	ticket.ClaimedBy = c.ClaimedBy.ID
+	notification := models.Notification{
+		Title:     "You've been claimed!",
+		Body:      queue.Course.Code,
+		Timestamp: time.Now(),
+		Type:      models.NotificationClaimed,
+	}
+	if err := mr.addNotification(ticket.User.UserID, notification); err != nil {
+		glog.Warningf("error sending claim notification: %v\n", err)
+	}
+
+	mr.publish(c.QueueID)
+	return copyTicket(ticket), nil
+}
+
+func (mr *MemoryRepository) EditTicket(c *models.EditTicketRequest) error {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	// Validate that this is a valid queue.
+	queue, ok := mr.queues[c.QueueID]
+	if !ok {
+		return qerrors.InvalidQueueError
+	}
+	ticket, ok := mr.tickets[c.QueueID][c.ID]
+	if !ok {
+		return qerrors.TicketNotFoundError
+	}
+
+	// Validate that the editing user may move the ticket to the new status.
+	if err := checkTransition(ticket.Status, c.Status, ticketActorOf(c.ClaimedBy, queue, ticket)); err != nil {
+		return err
+	}
+
+	description := editedDescription(ticket, c)
+	mr.addTicketEvent(c.QueueID, newTicketEvent(c.ID, models.TicketEdited, c.ClaimedBy, ticket.Status, c.Status, ticket.Description, description))
+	ticket.Status = c.Status
+	ticket.Description = description
+
+	if c.Status == models.StatusClaimed {
+		// The ticket is being claimed.
+		ticket.ClaimedAt = time.Now()
+		ticket.ClaimedBy = c.ClaimedBy.ID
+		notification := models.Notification{
+			Title:     "You've been claimed!",
+			Body:      queue.Course.Code,
+			Timestamp: time.Now(),
+			Type:      models.NotificationClaimed,
+		}
+		err := mr.addNotification(ticket.User.UserID, notification)
+		if err != nil {
+			glog.Warningf("error sending claim notification: %v\n", err)
+		}
+	} else if c.Status == models.StatusComplete {
+		// Ticket is being marked complete.
+		ticket.CompletedAt = time.Now()
+
+		// Remove the ticket from the visible tickets array and move it to the completed tickets array.
+		queue.PendingTickets = removeString(queue.PendingTickets, c.ID)
+		queue.CompletedTickets = appendUnique(queue.CompletedTickets, c.ID)
+	}
+
+	mr.publish(c.QueueID)
+	return nil
+}
+
+func (mr *MemoryRepository) DeleteTicket(c *models.DeleteTicketRequest) error {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	queue, ok := mr.queues[c.QueueID]
+	if !ok {
+		return qerrors.InvalidQueueError
+	}
+	ticket, ok := mr.tickets[c.QueueID][c.ID]
+	if !ok {
+		return qerrors.TicketNotFoundError
+	}
+
+	// The ticket's history is kept after it is deleted.
+	mr.addTicketEvent(c.QueueID, newTicketEvent(c.ID, models.TicketDeleted, c.DeletedBy, ticket.Status, "", "", ""))
+	queue.PendingTickets = removeString(queue.PendingTickets, c.ID)
+	delete(mr.tickets[c.QueueID], c.ID)
+	mr.publish(c.QueueID)
+	return nil
+}
+
+func (mr *MemoryRepository) MakeAnnouncement(c *models.MakeAnnouncementRequest) error {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	// Get queue.
+	queue, ok := mr.queues[c.QueueID]
+	if !ok {
+		return qerrors.InvalidQueueError
+	}
+
+	// Reject empty announcements.
+	if len(c.Announcement) == 0 {
+		return qerrors.InvalidBody
+	}
+
+	for _, ticketID := range queue.PendingTickets {
+		ticket, ok := mr.tickets[c.QueueID][ticketID]
+		// If ticket is completed, ignore.
+		if !ok || ticket.Status == models.StatusComplete {
+			continue
+		}
+		// Add an announcement to the owner of the ticket.
+		notification := models.Notification{
+			Title:     c.Announcement,
+			Body:      queue.Course.Code,
+			Timestamp: time.Now(),
+			Type:      models.NotificationAnnouncement,
+		}
+		_ = mr.addNotification(ticket.User.UserID, notification)
+	}
+	return nil
+}
+
+// GetQueue gets the Queue corresponding to the provided queue ID.
+func (mr *MemoryRepository) GetQueue(ID string) (*models.Queue, error) {
+	mr.lock.RLock()
+	defer mr.lock.RUnlock()
+
+	queue, ok := mr.queues[ID]
+	if !ok {
+		return nil, qerrors.QueueNotFoundError
+	}
+	return copyQueue(queue), nil
+}
+
+// GetTicket gets the Ticket with the given ID from the given queue.
+func (mr *MemoryRepository) GetTicket(queueID string, ticketID string) (*models.Ticket, error) {
+	mr.lock.RLock()
+	defer mr.lock.RUnlock()
+
+	ticket, ok := mr.tickets[queueID][ticketID]
+	if !ok {
+		return nil, qerrors.TicketNotFoundError
+	}
+	return copyTicket(ticket), nil
+}
+
+// WatchQueue sends a snapshot of the queue and its tickets on the returned channel whenever either changes, until
+// ctx is done or the queue is deleted. Snapshots that the receiver has not yet read are replaced by newer ones.
+func (mr *MemoryRepository) WatchQueue(ctx context.Context, queueID string) (<-chan *models.QueueSnapshot, error) {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	if _, ok := mr.queues[queueID]; !ok {
+		return nil, qerrors.QueueNotFoundError
+	}
+
+	ch := make(chan *models.QueueSnapshot, 1)
+	stop := make(chan struct{})
+	if mr.watchers[queueID] == nil {
+		mr.watchers[queueID] = make(map[chan *models.QueueSnapshot]chan struct{})
+	}
+	mr.watchers[queueID][ch] = stop
+	ch <- mr.snapshot(queueID)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stop:
+			// The queue was deleted, which closed the channel.
+			return
+		}
+
+		mr.lock.Lock()
+		defer mr.lock.Unlock()
+		// The channel has already been closed if the queue was deleted in the meantime.
+		if _, ok := mr.watchers[queueID][ch]; ok {
+			delete(mr.watchers[queueID], ch)
+			close(ch)
+		}
+	}()
+
+	return ch, nil
+}
+
+// publish sends a new snapshot of the queue to each of its watchers. The caller must hold the write lock.
+func (mr *MemoryRepository) publish(queueID string) {
+	if len(mr.watchers[queueID]) == 0 {
+		return
+	}
+
+	snapshot := mr.snapshot(queueID)
+	for ch := range mr.watchers[queueID] {
+		// Replace any snapshot the watcher hasn't read yet.
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- snapshot
+	}
+}
+
+// snapshot copies the current state of a queue and its tickets. The caller must hold the lock.
+func (mr *MemoryRepository) snapshot(queueID string) *models.QueueSnapshot {
+	tickets := make(map[string]*models.Ticket, len(mr.tickets[queueID]))
+	for id, ticket := range mr.tickets[queueID] {
+		tickets[id] = copyTicket(ticket)
+	}
+
+	return &models.QueueSnapshot{
+		Queue:   copyQueue(mr.queues[queueID]),
+		Tickets: tickets,
+	}
+}
+
+func (mr *MemoryRepository) GetPendingTickets(queueID string) ([]*models.Ticket, error) {
+	mr.lock.RLock()
+	defer mr.lock.RUnlock()
+
+	if _, ok := mr.queues[queueID]; !ok {
+		return nil, qerrors.QueueNotFoundError
+	}
+
+	pending := mr.pendingTickets(queueID)
+	tickets := make([]*models.Ticket, 0, len(pending))
+	for _, t := range pending {
+		tickets = append(tickets, copyTicket(t))
+	}
+	return tickets, nil
+}
+
+func (mr *MemoryRepository) GetTickets(queueID string, from time.Time, to time.Time) ([]*models.Ticket, error) {
+	mr.lock.RLock()
+	defer mr.lock.RUnlock()
+
+	tickets := make([]*models.Ticket, 0)
+	for _, t := range mr.tickets[queueID] {
+		if (from.IsZero() || !t.CreatedAt.Before(from)) && (to.IsZero() || t.CreatedAt.Before(to)) {
+			tickets = append(tickets, copyTicket(t))
+		}
+	}
+	sort.Slice(tickets, func(i, j int) bool {
+		return tickets[i].CreatedAt.Before(tickets[j].CreatedAt)
+	})
+	return tickets, nil
+}
+
+func (mr *MemoryRepository) GetWaitEstimate(queueID string) (*models.WaitEstimate, error) {
+	mr.lock.RLock()
+	defer mr.lock.RUnlock()
+
+	if _, ok := mr.queues[queueID]; !ok {
+		return nil, qerrors.QueueNotFoundError
+	}
+
+	var completed []*models.Ticket
+	for _, t := range mr.tickets[queueID] {
+		if t.Status == models.StatusComplete {
+			completed = append(completed, t)
+		}
+	}
+
+	return estimateWait(queueID, mr.pendingTickets(queueID), completed, time.Now()), nil
+}
+
+func (mr *MemoryRepository) GetTicketEvents(queueID string, ticketID string) ([]*models.TicketEvent, error) {
+	mr.lock.RLock()
+	defer mr.lock.RUnlock()
+
+	events := make([]*models.TicketEvent, 0, len(mr.events[queueID][ticketID]))
+	for _, event := range mr.events[queueID][ticketID] {
+		e := *event
+		events = append(events, &e)
+	}
+	return events, nil
+}
+
+// addTicketEvent appends an event to a ticket's history. The caller must hold the write lock.
+func (mr *MemoryRepository) addTicketEvent(queueID string, event *models.TicketEvent) {
+	event.ID = newMemoryID()
+	mr.events[queueID][event.TicketID] = append(mr.events[queueID][event.TicketID], event)
+}
+
+// pendingTickets returns the queue's pending tickets, in order. The caller must hold the lock.
+func (mr *MemoryRepository) pendingTickets(queueID string) []*models.Ticket {
+	queue := mr.queues[queueID]
+	tickets := make([]*models.Ticket, 0, len(queue.PendingTickets))
+	for _, id := range queue.PendingTickets {
+		if t, ok := mr.tickets[queueID][id]; ok {
+			tickets = append(tickets, t)
+		}
+	}
+	return tickets
+}
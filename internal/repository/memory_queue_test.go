@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"signmeup/internal/models"
+)
+
+// newTestQueue creates a memory repository holding a course with an open queue, a student and a staff member of the
+// course.
+func newTestQueue(t *testing.T) (repo *MemoryRepository, queue *models.Queue, student *models.User, staff *models.User) {
+	t.Helper()
+
+	repo = NewMemoryRepository()
+	// The first user is a site admin, so the test users are created after it.
+	if _, err := repo.Create(&models.CreateUserRequest{Email: "admin@brown.edu", Password: "password", DisplayName: "Admin"}); err != nil {
+		t.Fatalf("creating admin: %v", err)
+	}
+	student, err := repo.Create(&models.CreateUserRequest{Email: "student@brown.edu", Password: "password", DisplayName: "Student"})
+	if err != nil {
+		t.Fatalf("creating student: %v", err)
+	}
+	staff, err = repo.Create(&models.CreateUserRequest{Email: "staff@brown.edu", Password: "password", DisplayName: "Staff"})
+	if err != nil {
+		t.Fatalf("creating staff: %v", err)
+	}
+
+	course, err := repo.CreateCourse(&models.CreateCourseRequest{Title: "Intro", Code: "cs0150", Term: "fall"})
+	if err != nil {
+		t.Fatalf("creating course: %v", err)
+	}
+	err = repo.AddPermission(&models.AddCoursePermissionRequest{
+		CourseID:   course.ID,
+		Email:      staff.Email,
+		Permission: string(models.CourseStaff),
+	})
+	if err != nil {
+		t.Fatalf("adding staff permission: %v", err)
+	}
+	// Reload the staff member for their new permission.
+	if staff, err = repo.GetUserByID(staff.ID); err != nil {
+		t.Fatalf("getting staff: %v", err)
+	}
+
+	queue, err = repo.CreateQueue(&models.CreateQueueRequest{Title: "Hours", CourseID: course.ID})
+	if err != nil {
+		t.Fatalf("creating queue: %v", err)
+	}
+	return repo, queue, student, staff
+}
+
+func TestCreateTicketConcurrently(t *testing.T) {
+	repo, queue, student, _ := newTestQueue(t)
+	testCreateTicketConcurrently(t, repo, queue, student)
+}
+
+func TestClaimNextTicketConcurrently(t *testing.T) {
+	repo, queue, student, staff := newTestQueue(t)
+	testClaimNextTicketConcurrently(t, repo, queue, student, staff)
+}
+
+func TestCompleteTicketConcurrently(t *testing.T) {
+	repo, queue, student, staff := newTestQueue(t)
+	testCompleteTicketConcurrently(t, repo, queue, student, staff)
+}
+
+func TestDeleteTicketConcurrently(t *testing.T) {
+	repo, queue, student, _ := newTestQueue(t)
+	testDeleteTicketConcurrently(t, repo, queue, student)
+}
+
+func TestWatchQueueStopsWhenQueueDeleted(t *testing.T) {
+	repo, queue, _, _ := newTestQueue(t)
+	goroutines := runtime.NumGoroutine()
+
+	// The context is never done, so only the queue's deletion can stop the watcher.
+	ch, err := repo.WatchQueue(context.Background(), queue.ID)
+	if err != nil {
+		t.Fatalf("watching queue: %v", err)
+	}
+	if err := repo.DeleteQueue(&models.DeleteQueueRequest{QueueID: queue.ID}); err != nil {
+		t.Fatalf("deleting queue: %v", err)
+	}
+	for range ch {
+		// Drain the snapshots sent before the channel was closed.
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > goroutines {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines are running after the queue was deleted, want %d", runtime.NumGoroutine(), goroutines)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"signmeup/internal/models"
+	"signmeup/internal/qerrors"
+	"time"
+)
+
+func (mr *MemoryRepository) GetSchedules(courseID string) ([]*models.Schedule, error) {
+	mr.lock.RLock()
+	defer mr.lock.RUnlock()
+
+	schedules := make([]*models.Schedule, 0, len(mr.schedules[courseID]))
+	for _, schedule := range mr.schedules[courseID] {
+		schedules = append(schedules, copySchedule(schedule))
+	}
+	return schedules, nil
+}
+
+func (mr *MemoryRepository) GetAllSchedules() ([]*models.Schedule, error) {
+	mr.lock.RLock()
+	defer mr.lock.RUnlock()
+
+	var schedules []*models.Schedule
+	for _, courseSchedules := range mr.schedules {
+		for _, schedule := range courseSchedules {
+			schedules = append(schedules, copySchedule(schedule))
+		}
+	}
+	return schedules, nil
+}
+
+func (mr *MemoryRepository) CreateSchedule(c *models.CreateScheduleRequest) (*models.Schedule, error) {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	if _, ok := mr.courses[c.CourseID]; !ok {
+		return nil, qerrors.CourseNotFoundError
+	}
+
+	schedule := &models.Schedule{
+		ID:             newMemoryID(),
+		CourseID:       c.CourseID,
+		Title:          c.Title,
+		Description:    c.Description,
+		Location:       c.Location,
+		Weekday:        c.Weekday,
+		StartTime:      c.StartTime,
+		EndTime:        c.EndTime,
+		TimeZone:       c.TimeZone,
+		FaceMaskPolicy: c.FaceMaskPolicy,
+		RejoinCooldown: c.RejoinCooldown,
+		Staff:          append([]string(nil), c.Staff...),
+		ExceptionDates: append([]string(nil), c.ExceptionDates...),
+	}
+	if err := validSchedule(schedule); err != nil {
+		return nil, err
+	}
+
+	if mr.schedules[c.CourseID] == nil {
+		mr.schedules[c.CourseID] = make(map[string]*models.Schedule)
+	}
+	mr.schedules[c.CourseID][schedule.ID] = schedule
+	return copySchedule(schedule), nil
+}
+
+func (mr *MemoryRepository) EditSchedule(c *models.EditScheduleRequest) error {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	schedule, ok := mr.schedules[c.CourseID][c.ScheduleID]
+	if !ok {
+		return qerrors.ScheduleNotFoundError
+	}
+
+	edited := copySchedule(schedule)
+	edited.Title = c.Title
+	edited.Description = c.Description
+	edited.Location = c.Location
+	edited.Weekday = c.Weekday
+	edited.StartTime = c.StartTime
+	edited.EndTime = c.EndTime
+	edited.TimeZone = c.TimeZone
+	edited.FaceMaskPolicy = c.FaceMaskPolicy
+	edited.RejoinCooldown = c.RejoinCooldown
+	edited.Staff = append([]string(nil), c.Staff...)
+	edited.ExceptionDates = append([]string(nil), c.ExceptionDates...)
+	if err := validSchedule(edited); err != nil {
+		return err
+	}
+
+	mr.schedules[c.CourseID][c.ScheduleID] = edited
+	return nil
+}
+
+func (mr *MemoryRepository) DeleteSchedule(c *models.DeleteScheduleRequest) error {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	delete(mr.schedules[c.CourseID], c.ScheduleID)
+	return nil
+}
+
+func (mr *MemoryRepository) StartScheduledSession(courseID string, scheduleID string, now time.Time) (*models.Queue, error) {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	schedule, ok := mr.schedules[courseID][scheduleID]
+	if !ok {
+		return nil, qerrors.ScheduleNotFoundError
+	}
+
+	date, _, end, ok := schedule.Occurrence(now)
+	if !ok {
+		return nil, qerrors.NoScheduledSessionError
+	}
+	if schedule.LastOccurrence == date {
+		return nil, qerrors.SessionAlreadyStartedError
+	}
+
+	queue, err := mr.createQueue(scheduledQueueRequest(schedule, end))
+	if err != nil {
+		return nil, err
+	}
+
+	schedule.LastOccurrence = date
+	schedule.LastQueueID = queue.ID
+	return queue, nil
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"signmeup/internal/models"
+	"signmeup/internal/qerrors"
+)
+
+func (mr *MemoryRepository) GetQueueTemplates(courseID string) ([]*models.QueueTemplate, error) {
+	mr.lock.RLock()
+	defer mr.lock.RUnlock()
+
+	templates := make([]*models.QueueTemplate, 0, len(mr.templates[courseID]))
+	for _, template := range mr.templates[courseID] {
+		templates = append(templates, copyQueueTemplate(template))
+	}
+	return templates, nil
+}
+
+func (mr *MemoryRepository) GetQueueTemplate(courseID string, templateID string) (*models.QueueTemplate, error) {
+	mr.lock.RLock()
+	defer mr.lock.RUnlock()
+
+	template, ok := mr.templates[courseID][templateID]
+	if !ok {
+		return nil, qerrors.QueueTemplateNotFoundError
+	}
+	return copyQueueTemplate(template), nil
+}
+
+func (mr *MemoryRepository) CreateQueueTemplate(c *models.CreateQueueTemplateRequest) (*models.QueueTemplate, error) {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	if err := validQueueTemplate(c.Name, c.OrderingPolicy, c.Categories); err != nil {
+		return nil, err
+	}
+	if _, ok := mr.courses[c.CourseID]; !ok {
+		return nil, qerrors.CourseNotFoundError
+	}
+
+	template := &models.QueueTemplate{
+		ID:                 newMemoryID(),
+		CourseID:           c.CourseID,
+		Name:               c.Name,
+		Title:              c.Title,
+		Description:        c.Description,
+		Location:           c.Location,
+		ShowMeetingLinks:   c.ShowMeetingLinks,
+		AllowTicketEditing: c.AllowTicketEditing,
+		FaceMaskPolicy:     c.FaceMaskPolicy,
+		RejoinCooldown:     c.RejoinCooldown,
+		OrderingPolicy:     c.OrderingPolicy,
+		Categories:         append([]string(nil), c.Categories...),
+	}
+
+	if mr.templates[c.CourseID] == nil {
+		mr.templates[c.CourseID] = make(map[string]*models.QueueTemplate)
+	}
+	mr.templates[c.CourseID][template.ID] = template
+	return copyQueueTemplate(template), nil
+}
+
+func (mr *MemoryRepository) EditQueueTemplate(c *models.EditQueueTemplateRequest) error {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	if err := validQueueTemplate(c.Name, c.OrderingPolicy, c.Categories); err != nil {
+		return err
+	}
+
+	template, ok := mr.templates[c.CourseID][c.TemplateID]
+	if !ok {
+		return qerrors.QueueTemplateNotFoundError
+	}
+
+	template.Name = c.Name
+	template.Title = c.Title
+	template.Description = c.Description
+	template.Location = c.Location
+	template.ShowMeetingLinks = c.ShowMeetingLinks
+	template.AllowTicketEditing = c.AllowTicketEditing
+	template.FaceMaskPolicy = c.FaceMaskPolicy
+	template.RejoinCooldown = c.RejoinCooldown
+	template.OrderingPolicy = c.OrderingPolicy
+	template.Categories = append([]string(nil), c.Categories...)
+	return nil
+}
+
+func (mr *MemoryRepository) DeleteQueueTemplate(c *models.DeleteQueueTemplateRequest) error {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	delete(mr.templates[c.CourseID], c.TemplateID)
+	return nil
+}
@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"signmeup/internal/models"
+	"signmeup/internal/qerrors"
+
+	"github.com/google/uuid"
+)
+
+func (mr *MemoryRepository) GetUserByID(id string) (*models.User, error) {
+	if err := validateID(id); err != nil {
+		return nil, err
+	}
+
+	mr.lock.RLock()
+	defer mr.lock.RUnlock()
+
+	user, ok := mr.users[id]
+	if !ok {
+		return nil, qerrors.UserNotFoundError
+	}
+	return copyUser(user), nil
+}
+
+// GetUserByEmail retrieves the User associated with the given email.
+func (mr *MemoryRepository) GetUserByEmail(email string) (*models.User, error) {
+	mr.lock.RLock()
+	defer mr.lock.RUnlock()
+
+	user := mr.userByEmail(email)
+	if user == nil {
+		return nil, qerrors.UserNotFoundError
+	}
+	return copyUser(user), nil
+}
+
+func (mr *MemoryRepository) GetIDByEmail(email string) (string, error) {
+	user, err := mr.GetUserByEmail(email)
+	if err != nil {
+		return "", err
+	}
+	return user.ID, nil
+}
+
+func (mr *MemoryRepository) UpdateUser(r *models.UpdateUserRequest) error {
+	if r.DisplayName == "" {
+		return qerrors.InvalidDisplayName
+	}
+
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	user, ok := mr.users[r.UserID]
+	if !ok {
+		return qerrors.UserNotFoundError
+	}
+
+	user.DisplayName = r.DisplayName
+	user.Pronouns = r.Pronouns
+	user.MeetingLink = r.MeetingLink
+	return nil
+}
+
+// MakeAdminByEmail makes the user with the given email a site admin.
+func (mr *MemoryRepository) MakeAdminByEmail(u *models.MakeAdminByEmailRequest) error {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	user := mr.userByEmail(u.Email)
+	if user == nil {
+		return qerrors.UserNotFoundError
+	}
+
+	diff := make(map[string]models.AuditChange)
+	addAuditChange(diff, "isAdmin", user.IsAdmin, u.IsAdmin)
+	user.IsAdmin = u.IsAdmin
+
+	mr.addAuditLog(newAuditLogEntry(models.AuditMakeAdmin, u.Actor, "", u.Email, diff))
+	return nil
+}
+
+func (mr *MemoryRepository) Count() int {
+	mr.lock.RLock()
+	defer mr.lock.RUnlock()
+	return len(mr.users)
+}
+
+func (mr *MemoryRepository) List() ([]*models.User, error) {
+	mr.lock.RLock()
+	defer mr.lock.RUnlock()
+
+	var users []*models.User
+	for _, user := range mr.users {
+		users = append(users, copyUser(user))
+	}
+	return users, nil
+}
+
+// Create registers a new user. As with a user's first sign in through Firebase, the first registered user becomes a
+// site admin, and any pending course invites for the user's email are redeemed.
+func (mr *MemoryRepository) Create(u *models.CreateUserRequest) (*models.User, error) {
+	if err := validate(u); err != nil {
+		return nil, err
+	}
+
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	if mr.userByEmail(u.Email) != nil {
+		return nil, fmt.Errorf("error creating user: email %q already exists\n", u.Email)
+	}
+
+	user := &models.User{
+		ID: newMemoryID(),
+		Profile: &models.Profile{
+			DisplayName:       u.DisplayName,
+			Email:             u.Email,
+			IsAdmin:           len(mr.users) == 0,
+			CoursePermissions: make(map[string]models.CoursePermission),
+			Notifications:     make([]models.Notification, 0),
+		},
+		CreationTimestamp:  time.Now().Unix(),
+		LastLogInTimestamp: time.Now().Unix(),
+	}
+	mr.users[user.ID] = user
+
+	// Go through each of the invites and execute them.
+	mr.redeemInvites(user)
+
+	return copyUser(user), nil
+}
+
+func (mr *MemoryRepository) Delete(id string) error {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	if _, ok := mr.users[id]; !ok {
+		return qerrors.DeleteUserError
+	}
+	delete(mr.users, id)
+	return nil
+}
+
+func (mr *MemoryRepository) AddFavoriteCourse(userID string, courseID string) error {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	user, ok := mr.users[userID]
+	if !ok {
+		return qerrors.UserNotFoundError
+	}
+	user.FavoriteCourses = appendUnique(user.FavoriteCourses, courseID)
+	return nil
+}
+
+func (mr *MemoryRepository) RemoveFavoriteCourse(userID string, courseID string) error {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	user, ok := mr.users[userID]
+	if !ok {
+		return qerrors.UserNotFoundError
+	}
+	user.FavoriteCourses = removeString(user.FavoriteCourses, courseID)
+	return nil
+}
+
+// Operations
+
+func (mr *MemoryRepository) AddNotification(userID string, notification models.Notification) error {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	return mr.addNotification(userID, notification)
+}
+
+// addNotification appends a notification to the given user's profile. The caller must hold the write lock.
+func (mr *MemoryRepository) addNotification(userID string, notification models.Notification) error {
+	user, ok := mr.users[userID]
+	if !ok {
+		return qerrors.UserNotFoundError
+	}
+
+	notification.ID = uuid.New().String()
+	user.Notifications = append(user.Notifications, notification)
+	return nil
+}
+
+func (mr *MemoryRepository) ClearNotification(c *models.ClearNotificationRequest) error {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	user, ok := mr.users[c.UserID]
+	if !ok {
+		return qerrors.UserNotFoundError
+	}
+
+	newNotifications := make([]models.Notification, 0)
+	for _, v := range user.Notifications {
+		if v.ID != c.NotificationID {
+			newNotifications = append(newNotifications, v)
+		}
+	}
+	user.Notifications = newNotifications
+	return nil
+}
+
+func (mr *MemoryRepository) ClearAllNotifications(c *models.ClearAllNotificationsRequest) error {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	user, ok := mr.users[c.UserID]
+	if !ok {
+		return qerrors.UserNotFoundError
+	}
+	user.Notifications = make([]models.Notification, 0)
+	return nil
+}
+
+// Helpers
+
+// userByEmail returns the user with the given email, or nil if there is none. The caller must hold the lock.
+func (mr *MemoryRepository) userByEmail(email string) *models.User {
+	for _, user := range mr.users {
+		if user.Email == email {
+			return user
+		}
+	}
+	return nil
+}
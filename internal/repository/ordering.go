@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"signmeup/internal/models"
+	"signmeup/internal/qerrors"
+	"time"
+)
+
+const (
+	// firstTimerWindow is how recently a student must have been helped to no longer count as a first-timer.
+	firstTimerWindow = 24 * time.Hour
+	// fewestTicketsWindow is the period over which tickets are counted for the fewest-tickets policy.
+	fewestTicketsWindow = 7 * 24 * time.Hour
+)
+
+// validOrderingPolicy returns an error if policy is not a known ordering policy. An empty policy is treated as FIFO.
+func validOrderingPolicy(policy models.OrderingPolicy) error {
+	switch policy {
+	case "", models.OrderFIFO, models.OrderFirstTimersFirst, models.OrderFewestTickets:
+		return nil
+	default:
+		return qerrors.InvalidOrderingPolicyError
+	}
+}
+
+// orderingHistoryWindow returns how far back a queue's tickets must be read to rank a new ticket under policy, or
+// zero if the policy does not depend on ticket history.
+func orderingHistoryWindow(policy models.OrderingPolicy) time.Duration {
+	switch policy {
+	case models.OrderFirstTimersFirst:
+		return firstTimerWindow
+	case models.OrderFewestTickets:
+		return fewestTicketsWindow
+	default:
+		return 0
+	}
+}
+
+// ticketRanker returns a function that ranks a ticket from the given user under policy, using the queue's recent
+// tickets as history. Tickets with lower ranks are helped first. exclude is the ID of the ticket being ranked, so that
+// it is not counted against itself. A nil function is returned for policies that do not rank tickets.
+func ticketRanker(policy models.OrderingPolicy, history []*models.Ticket, now time.Time) func(userID, exclude string) int {
+	switch policy {
+	case models.OrderFirstTimersFirst:
+		helped := make(map[string]bool)
+		for _, t := range history {
+			if t.Status == models.StatusComplete && now.Sub(t.CompletedAt) < firstTimerWindow {
+				helped[t.User.UserID] = true
+			}
+		}
+		return func(userID, exclude string) int {
+			if helped[userID] {
+				return 1
+			}
+			return 0
+		}
+	case models.OrderFewestTickets:
+		created := make(map[string][]string)
+		for _, t := range history {
+			if now.Sub(t.CreatedAt) < fewestTicketsWindow {
+				created[t.User.UserID] = append(created[t.User.UserID], t.ID)
+			}
+		}
+		return func(userID, exclude string) int {
+			rank := 0
+			for _, id := range created[userID] {
+				if id != exclude {
+					rank++
+				}
+			}
+			return rank
+		}
+	default:
+		return nil
+	}
+}
+
+// insertTicket returns a copy of the queue's pending tickets with ticketID inserted where the queue's ordering policy
+// places a new ticket from userID. The new ticket goes after every pending ticket that ranks the same or better, so
+// tickets of equal rank stay first-come first-served, and never ahead of a ticket that has already been claimed.
+func insertTicket(queue *models.Queue, history []*models.Ticket, ticketID, userID string, now time.Time) []string {
+	pending := append([]string{}, queue.PendingTickets...)
+
+	rank := ticketRanker(queue.OrderingPolicy, history, now)
+	if rank == nil {
+		return append(pending, ticketID)
+	}
+
+	tickets := make(map[string]*models.Ticket, len(history))
+	for _, t := range history {
+		tickets[t.ID] = t
+	}
+
+	newRank := rank(userID, "")
+	index := 0
+	for i, id := range pending {
+		t, ok := tickets[id]
+		// Tickets older than the history window are treated as ranking ahead of the new ticket.
+		if !ok || t.Status == models.StatusClaimed || rank(t.User.UserID, id) <= newRank {
+			index = i + 1
+		}
+	}
+
+	pending = append(pending, "")
+	copy(pending[index+1:], pending[index:])
+	pending[index] = ticketID
+	return pending
+}
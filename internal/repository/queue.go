@@ -1,15 +1,21 @@
 package repository
 
 import (
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/binary"
 	"fmt"
 	"math/rand"
 	"signmeup/internal/firebase"
 	"signmeup/internal/models"
 	"signmeup/internal/qerrors"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang/glog"
-	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"cloud.google.com/go/firestore"
 	"github.com/mitchellh/mapstructure"
@@ -20,40 +26,18 @@ func (fr *FirebaseRepository) CreateQueue(c *models.CreateQueueRequest) (queue *
 	if err != nil {
 		return nil, err
 	}
-
-	queue = &models.Queue{
-		Title:              c.Title,
-		Description:        c.Description,
-		Location:           c.Location,
-		EndTime:            c.EndTime,
-		CourseID:           queueCourse.ID,
-		AllowTicketEditing: c.AllowTicketEditing,
-		ShowMeetingLinks:   c.ShowMeetingLinks,
-		Course:             queueCourse,
-		IsCutOff:           false,
-		FaceMaskPolicy:     c.FaceMaskPolicy,
-		RejoinCooldown:     c.RejoinCooldown,
+	if err := checkNotArchived(queueCourse); err != nil {
+		return nil, err
+	}
+	if err := validOrderingPolicy(c.OrderingPolicy); err != nil {
+		return nil, err
+	}
+	if err := validCategories(c.Categories); err != nil {
+		return nil, err
 	}
 
-	ref, _, err := fr.firestoreClient.Collection(models.FirestoreQueuesCollection).Add(firebase.Context, map[string]interface{}{
-		"title":       queue.Title,
-		"description": queue.Description,
-		"location":    queue.Location,
-		"endTime":     queue.EndTime,
-		"courseID":    queue.CourseID,
-		"course": map[string]interface{}{
-			"id":    queue.Course.ID,
-			"title": queue.Course.Title,
-			"code":  queue.Course.Code,
-		},
-		"completedTickets":   []string{},
-		"pendingTickets":     []string{},
-		"isCutOff":           queue.IsCutOff,
-		"allowTicketEditing": queue.AllowTicketEditing,
-		"showMeetingLinks":   queue.ShowMeetingLinks,
-		"faceMaskPolicy":     queue.FaceMaskPolicy,
-		"rejoinCooldown":     queue.RejoinCooldown,
-	})
+	queue = newQueue(c, queueCourse)
+	ref, _, err := fr.firestoreClient.Collection(models.FirestoreQueuesCollection).Add(firebase.Context, queueDocument(queue))
 	if err != nil {
 		return nil, fmt.Errorf("error creating queue: %v", err)
 	}
@@ -64,6 +48,13 @@ func (fr *FirebaseRepository) CreateQueue(c *models.CreateQueueRequest) (queue *
 }
 
 func (fr *FirebaseRepository) EditQueue(c *models.EditQueueRequest) error {
+	if err := validOrderingPolicy(c.OrderingPolicy); err != nil {
+		return err
+	}
+	if err := validCategories(c.Categories); err != nil {
+		return err
+	}
+
 	// Update queue.
 	_, err := fr.firestoreClient.Collection(models.FirestoreQueuesCollection).Doc(c.QueueID).Update(firebase.Context, []firestore.Update{
 		{
@@ -96,6 +87,14 @@ func (fr *FirebaseRepository) EditQueue(c *models.EditQueueRequest) error {
 			Path:  "rejoinCooldown",
 			Value: c.RejoinCooldown,
 		},
+		{
+			Path:  "orderingPolicy",
+			Value: c.OrderingPolicy,
+		},
+		{
+			Path:  "categories",
+			Value: c.Categories,
+		},
 	})
 	return err
 }
@@ -107,45 +106,162 @@ func (fr *FirebaseRepository) DeleteQueue(c *models.DeleteQueueRequest) error {
 	return err
 }
 
+// CutoffQueue sets whether a queue is cut off. A conditional cutoff, with EndedBy set, reads and updates the queue in a
+// transaction, so that when several instances try to close the same queue only one succeeds.
 func (fr *FirebaseRepository) CutoffQueue(c *models.CutoffQueueRequest) error {
-	_, err := fr.firestoreClient.Collection(models.FirestoreQueuesCollection).Doc(c.QueueID).Update(firebase.Context, []firestore.Update{
-		{Path: "isCutOff", Value: c.IsCutOff},
+	queueRef := fr.firestoreClient.Collection(models.FirestoreQueuesCollection).Doc(c.QueueID)
+	if c.EndedBy.IsZero() {
+		_, err := queueRef.Update(firebase.Context, []firestore.Update{
+			{Path: "isCutOff", Value: c.IsCutOff},
+		})
+		return err
+	}
+
+	return fr.firestoreClient.RunTransaction(firebase.Context, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(queueRef)
+		if status.Code(err) == codes.NotFound {
+			return qerrors.QueueNotFoundError
+		} else if err != nil {
+			return err
+		}
+		queue, err := decodeQueue(doc)
+		if err != nil {
+			return err
+		}
+		if !hasEnded(queue, c.EndedBy) {
+			return qerrors.QueueNotEndedError
+		}
+
+		return tx.Update(queueRef, []firestore.Update{
+			{Path: "isCutOff", Value: c.IsCutOff},
+		})
 	})
-	return err
 }
 
-func (fr *FirebaseRepository) ShuffleQueue(c *models.ShuffleQueueRequest) error {
-	q, err := fr.GetQueue(c.QueueID)
+// GetEndedQueues gets the queues that are still open even though their end time has passed.
+func (fr *FirebaseRepository) GetEndedQueues(now time.Time) ([]*models.Queue, error) {
+	// Only open queues are queried, and their end times are compared here, to avoid needing a composite index.
+	docs, err := fr.firestoreClient.Collection(models.FirestoreQueuesCollection).Where("isCutOff", "==", false).
+		Documents(firebase.Context).GetAll()
 	if err != nil {
-		return qerrors.InvalidQueueError
+		return nil, err
 	}
 
-	rand.Shuffle(len(q.PendingTickets), func(i, j int) {
-		q.PendingTickets[i], q.PendingTickets[j] = q.PendingTickets[j], q.PendingTickets[i]
-	})
+	var queues []*models.Queue
+	for _, doc := range docs {
+		queue, err := decodeQueue(doc)
+		if err != nil {
+			return nil, err
+		}
+		if hasEnded(queue, now) {
+			queues = append(queues, queue)
+		}
+	}
+	return queues, nil
+}
 
-	// TODO: This shuffle operation is not atomic. It reads the current PendingTickets array, shuffles it, and writes
-	// it back.
-	_, err = fr.firestoreClient.Collection(models.FirestoreQueuesCollection).Doc(c.QueueID).Update(firebase.Context, []firestore.Update{
-		{
-			Path:  "pendingTickets",
-			Value: q.PendingTickets,
-		},
-	})
+// GetCourseQueues gets every queue of a course, open or not.
+func (fr *FirebaseRepository) GetCourseQueues(courseID string) ([]*models.Queue, error) {
+	docs, err := fr.firestoreClient.Collection(models.FirestoreQueuesCollection).Where("courseID", "==", courseID).
+		Documents(firebase.Context).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	queues := make([]*models.Queue, 0, len(docs))
+	for _, doc := range docs {
+		queue, err := decodeQueue(doc)
+		if err != nil {
+			return nil, err
+		}
+		queues = append(queues, queue)
+	}
+	return queues, nil
+}
+
+// ShuffleQueue randomly reorders a queue's pending tickets in a transaction, so tickets created during the shuffle are
+// not lost. The seed used, along with the order before and after the shuffle, is recorded in the queue's shuffles
+// collection.
+func (fr *FirebaseRepository) ShuffleQueue(c *models.ShuffleQueueRequest) (*models.ShuffleRecord, error) {
+	queueRef := fr.firestoreClient.Collection(models.FirestoreQueuesCollection).Doc(c.QueueID)
+	recordRef := queueRef.Collection(models.FirestoreShufflesCollection).NewDoc()
+	seed := newShuffleSeed()
 
+	var record *models.ShuffleRecord
+	err := fr.firestoreClient.RunTransaction(firebase.Context, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(queueRef)
+		if err != nil {
+			return qerrors.InvalidQueueError
+		}
+		q, err := decodeQueue(doc)
+		if err != nil {
+			return err
+		}
+
+		record = &models.ShuffleRecord{
+			ID:         recordRef.ID,
+			QueueID:    c.QueueID,
+			Seed:       seed,
+			Before:     q.PendingTickets,
+			After:      shuffleTickets(q.PendingTickets, seed),
+			ShuffledBy: c.ShuffledBy.ID,
+			ShuffledAt: time.Now(),
+		}
+
+		err = tx.Update(queueRef, []firestore.Update{
+			{
+				Path:  "pendingTickets",
+				Value: record.After,
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("error shuffling queue: %v", err)
+		}
+
+		return tx.Create(recordRef, map[string]interface{}{
+			"queueID":    record.QueueID,
+			"seed":       record.Seed,
+			"before":     record.Before,
+			"after":      record.After,
+			"shuffledBy": record.ShuffledBy,
+			"shuffledAt": record.ShuffledAt,
+		})
+	})
 	if err != nil {
-		return fmt.Errorf("error shuffling queue: %v", err)
+		return nil, err
 	}
 
-	return nil
+	return record, nil
 }
 
-func (fr *FirebaseRepository) CreateTicket(c *models.CreateTicketRequest) (ticket *models.Ticket, err error) {
-	// Get the queue that this ticket belongs to.
-	queue, err := fr.GetQueue(c.QueueID)
+// GetShuffleRecords returns the shuffles of a queue, most recent first.
+func (fr *FirebaseRepository) GetShuffleRecords(queueID string) ([]*models.ShuffleRecord, error) {
+	docs, err := fr.firestoreClient.Collection(models.FirestoreQueuesCollection).Doc(queueID).Collection(models.FirestoreShufflesCollection).
+		OrderBy("shuffledAt", firestore.Desc).Documents(firebase.Context).GetAll()
 	if err != nil {
-		return nil, qerrors.InvalidQueueError
+		return nil, err
+	}
+
+	records := make([]*models.ShuffleRecord, 0, len(docs))
+	for _, doc := range docs {
+		var record models.ShuffleRecord
+		err = mapstructure.Decode(doc.Data(), &record)
+		if err != nil {
+			return nil, err
+		}
+		record.ID = doc.Ref.ID
+		records = append(records, &record)
 	}
+	return records, nil
+}
+
+// CreateTicket adds a ticket to a queue, at the position given by the queue's ordering policy. The check for an
+// existing ticket or an unelapsed cooldown, the creation of the ticket and its addition to the queue's pending tickets
+// all happen in a single transaction, so concurrent requests from the same user cannot create more than one ticket.
+func (fr *FirebaseRepository) CreateTicket(c *models.CreateTicketRequest) (ticket *models.Ticket, err error) {
+	queueRef := fr.firestoreClient.Collection(models.FirestoreQueuesCollection).Doc(c.QueueID)
+	ticketsRef := queueRef.Collection(models.FirestoreTicketsCollection)
+	ticketRef := ticketsRef.NewDoc()
 
 	userdata := models.TicketUserdata{
 		UserID:      c.CreatedBy.ID,
@@ -155,145 +271,349 @@ func (fr *FirebaseRepository) CreateTicket(c *models.CreateTicketRequest) (ticke
 		Pronouns:    c.CreatedBy.Pronouns,
 	}
 
-	ticket = &models.Ticket{
-		Queue:       queue,
-		User:        userdata,
-		CreatedAt:   time.Now(),
-		Status:      models.StatusWaiting,
-		Description: c.Description,
-		Anonymize:   c.Anonymize,
-	}
+	err = fr.firestoreClient.RunTransaction(firebase.Context, func(ctx context.Context, tx *firestore.Transaction) error {
+		// Get the queue that this ticket belongs to.
+		queueDoc, err := tx.Get(queueRef)
+		if err != nil {
+			return qerrors.InvalidQueueError
+		}
+		queue, err := decodeQueue(queueDoc)
+		if err != nil {
+			return err
+		}
+		if isClosed(queue, time.Now()) {
+			return qerrors.QueueClosedError
+		}
 
-	// Check that this user is not already in the queue.
-	iter := fr.firestoreClient.Collection(models.FirestoreQueuesCollection).Doc(c.QueueID).Collection(models.FirestoreTicketsCollection).Documents(firebase.Context)
-	for {
-		// Get next document
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
+		// The queue's copy of its course may be out of date, so the course itself is read for its roster and whether it
+		// is archived.
+		courseDoc, err := tx.Get(fr.firestoreClient.Collection(models.FirestoreCoursesCollection).Doc(queue.CourseID))
+		if status.Code(err) == codes.NotFound {
+			return qerrors.CourseNotFoundError
+		} else if err != nil {
+			return err
+		}
+		var course models.Course
+		if err := mapstructure.Decode(courseDoc.Data(), &course); err != nil {
+			return err
+		}
+		course.ID = courseDoc.Ref.ID
+		if err := checkNotArchived(&course); err != nil {
+			return err
+		}
+		if err := checkEnrollment(&course, c.CreatedBy); err != nil {
+			return err
 		}
+		if err := checkCategory(queue, c.Category); err != nil {
+			return err
+		}
+
+		// Check that this user is not already in the queue.
+		docs, err := tx.Documents(ticketsRef.Where("user.UserID", "==", c.CreatedBy.ID)).GetAll()
 		if err != nil {
 			glog.Warningf("an error occurred while checking for duplicate tickets: %v\n", err)
-			return nil, err
+			return err
 		}
-		// Check if matches user.
-		var ticket models.Ticket
-		err = mapstructure.Decode(doc.Data(), &ticket)
-		if err != nil {
-			return nil, err
+		for _, doc := range docs {
+			existing, err := decodeTicket(doc)
+			if err != nil {
+				return err
+			}
+			if err := checkRejoin(queue, existing, c.CreatedBy.ID); err != nil {
+				return err
+			}
 		}
 
-		// Check if any ticket violates the queue cooldown.
-		createdByCurrentUser := ticket.User.UserID == c.CreatedBy.ID
-		ticketIsComplete := ticket.Status == models.StatusComplete
-		canNeverRejoin := queue.RejoinCooldown == -1
-		cooldownNotElapsed := time.Now().Sub(ticket.CompletedAt).Minutes() < float64(queue.RejoinCooldown)
+		// Get the recent tickets that the queue's ordering policy ranks the new ticket against.
+		now := time.Now()
+		var history []*models.Ticket
+		if window := orderingHistoryWindow(queue.OrderingPolicy); window > 0 {
+			docs, err := tx.Documents(ticketsRef.Where("createdAt", ">=", now.Add(-window))).GetAll()
+			if err != nil {
+				return err
+			}
+			for _, doc := range docs {
+				t, err := decodeTicket(doc)
+				if err != nil {
+					return err
+				}
+				history = append(history, t)
+			}
+		}
 
-		if createdByCurrentUser && ticketIsComplete && (canNeverRejoin || cooldownNotElapsed) {
-			return nil, qerrors.QueueCooldownError
+		ticket = &models.Ticket{
+			ID:          ticketRef.ID,
+			Queue:       queue,
+			User:        userdata,
+			CreatedAt:   now,
+			Status:      models.StatusWaiting,
+			Description: c.Description,
+			Anonymize:   c.Anonymize,
+			Category:    c.Category,
 		}
 
-		// Errors if the current user has an incomplete ticket in the queue
-		if createdByCurrentUser && !ticketIsComplete {
-			return nil, qerrors.ActiveTicketError
+		// Add ticket to the queue's ticket collection
+		err = tx.Create(ticketRef, map[string]interface{}{
+			"user":        ticket.User,
+			"createdAt":   ticket.CreatedAt,
+			"status":      ticket.Status,
+			"description": ticket.Description,
+			"anonymize":   ticket.Anonymize,
+			"category":    ticket.Category,
+		})
+		if err != nil {
+			return fmt.Errorf("error creating ticket: %v", err)
 		}
-	}
 
-	// Add ticket to the queue's ticket collection
-	ref, _, err := fr.firestoreClient.Collection(models.FirestoreQueuesCollection).Doc(c.QueueID).Collection(models.FirestoreTicketsCollection).Add(firebase.Context, map[string]interface{}{
-		"user":        ticket.User,
-		"createdAt":   ticket.CreatedAt,
-		"status":      ticket.Status,
-		"description": ticket.Description,
-		"anonymize":   ticket.Anonymize,
+		event := newTicketEvent(ticket.ID, models.TicketCreated, c.CreatedBy, "", ticket.Status, "", ticket.Description)
+		if err := createTicketEvent(tx, ticketRef, event); err != nil {
+			return err
+		}
+
+		// Add ticket to the queue's pending tickets array. The whole array is written, since the ticket may not go at
+		// the end, and the transaction guarantees it has not changed since it was read.
+		pending := insertTicket(queue, history, ticket.ID, c.CreatedBy.ID, now)
+		err = tx.Update(queueRef, []firestore.Update{
+			{Path: "pendingTickets", Value: pending},
+		})
+		if err != nil {
+			return err
+		}
+
+		// The queue was read before the ticket was added to it.
+		ticket.Queue.PendingTickets = pending
+		return nil
 	})
 	if err != nil {
-		return nil, fmt.Errorf("error creating ticket: %v", err)
+		return nil, err
 	}
 
-	// Add ticket to the queue's ticket array and the queue's visible tickets array
-	_, err = fr.firestoreClient.Collection(models.FirestoreQueuesCollection).Doc(c.QueueID).Update(firebase.Context, []firestore.Update{
-		{Path: "pendingTickets", Value: firestore.ArrayUnion(ref.ID)},
-	})
+	return ticket, nil
+}
+
+// ClaimNextTicket claims the first waiting or returned ticket in the queue, optionally limited to a category, in a
+// single transaction, so two staff members claiming at once never claim the same ticket.
+func (fr *FirebaseRepository) ClaimNextTicket(c *models.ClaimNextTicketRequest) (*models.Ticket, error) {
+	queueRef := fr.firestoreClient.Collection(models.FirestoreQueuesCollection).Doc(c.QueueID)
 
+	var queue *models.Queue
+	var ticket *models.Ticket
+	err := fr.firestoreClient.RunTransaction(firebase.Context, func(ctx context.Context, tx *firestore.Transaction) error {
+		queueDoc, err := tx.Get(queueRef)
+		if err != nil {
+			return qerrors.InvalidQueueError
+		}
+		queue, err = decodeQueue(queueDoc)
+		if err != nil {
+			return err
+		}
+		if c.Category != "" {
+			if err := checkCategory(queue, c.Category); err != nil {
+				return err
+			}
+		}
+
+		refs := make([]*firestore.DocumentRef, 0, len(queue.PendingTickets))
+		for _, id := range queue.PendingTickets {
+			refs = append(refs, queueRef.Collection(models.FirestoreTicketsCollection).Doc(id))
+		}
+		docs, err := tx.GetAll(refs)
+		if err != nil {
+			return err
+		}
+
+		pending := make([]*models.Ticket, 0, len(docs))
+		for _, doc := range docs {
+			if !doc.Exists() {
+				continue
+			}
+			t, err := decodeTicket(doc)
+			if err != nil {
+				return err
+			}
+			pending = append(pending, t)
+		}
+
+		ticket = nextClaimableTicket(pending, c.Category)
+		if ticket == nil {
+			return qerrors.NoWaitingTicketsError
+		}
+		if err := checkTransition(ticket.Status, models.StatusClaimed, ticketActorOf(c.ClaimedBy, queue, ticket)); err != nil {
+			return err
+		}
+
+		ticketRef := queueRef.Collection(models.FirestoreTicketsCollection).Doc(ticket.ID)
+		event := newTicketEvent(ticket.ID, models.TicketEdited, c.ClaimedBy, ticket.Status, models.StatusClaimed, "", "")
+		if err := createTicketEvent(tx, ticketRef, event); err != nil {
+			return err
+		}
+
+		ticket.Status = models.StatusClaimed
+		ticket.ClaimedAt = time.Now()
+		ticket.ClaimedBy = c.ClaimedBy.ID
+		return tx.Update(ticketRef, []firestore.Update{
+			{Path: "status", Value: ticket.Status},
+			{Path: "claimedAt", Value: ticket.ClaimedAt},
+			{Path: "claimedBy", Value: ticket.ClaimedBy},
+		})
+	})
 	if err != nil {
-		glog.Errorf("error adding ticket to queue: %v\n", err)
-		return nil, fmt.Errorf("error adding ticket to queue: %v", err)
+		return nil, err
 	}
 
-	return
+	notification := models.Notification{
+		Title:     "You've been claimed!",
+		Body:      queue.Course.Code,
+		Timestamp: time.Now(),
+		Type:      models.NotificationClaimed,
+	}
+	if err := fr.AddNotification(ticket.User.UserID, notification); err != nil {
+		glog.Warningf("error sending claim notification: %v\n", err)
+	}
+
+	return ticket, nil
 }
 
+// EditTicket updates a ticket's status and description, if the editing user may make the change. When a ticket is
+// completed, the ticket and the queue's pending and completed tickets arrays are updated in a single transaction.
 func (fr *FirebaseRepository) EditTicket(c *models.EditTicketRequest) error {
-	// Validate that this is a valid queue.
-	queue, err := fr.GetQueue(c.QueueID)
-	if err != nil {
-		return qerrors.InvalidQueueError
-	}
+	queueRef := fr.firestoreClient.Collection(models.FirestoreQueuesCollection).Doc(c.QueueID)
+	ticketRef := queueRef.Collection(models.FirestoreTicketsCollection).Doc(c.ID)
 
-	ticketUpdates := []firestore.Update{
-		{
-			Path:  "status",
-			Value: c.Status,
-		}, {
-			Path:  "description",
-			Value: c.Description,
-		},
+	var queue *models.Queue
+	var ticket *models.Ticket
+	err := fr.firestoreClient.RunTransaction(firebase.Context, func(ctx context.Context, tx *firestore.Transaction) error {
+		// Validate that this is a valid queue.
+		queueDoc, err := tx.Get(queueRef)
+		if err != nil {
+			return qerrors.InvalidQueueError
+		}
+		queue, err = decodeQueue(queueDoc)
+		if err != nil {
+			return err
+		}
+
+		// Validate that the ticket exists.
+		ticketDoc, err := tx.Get(ticketRef)
+		if status.Code(err) == codes.NotFound {
+			return qerrors.TicketNotFoundError
+		} else if err != nil {
+			return err
+		}
+		ticket, err = decodeTicket(ticketDoc)
+		if err != nil {
+			return err
+		}
+
+		// Validate that the editing user may move the ticket to the new status.
+		if err := checkTransition(ticket.Status, c.Status, ticketActorOf(c.ClaimedBy, queue, ticket)); err != nil {
+			return err
+		}
+		description := editedDescription(ticket, c)
+
+		ticketUpdates := []firestore.Update{
+			{
+				Path:  "status",
+				Value: c.Status,
+			}, {
+				Path:  "description",
+				Value: description,
+			},
+		}
+
+		if c.Status == models.StatusClaimed {
+			// The ticket is being claimed.
+			ticketUpdates = append(ticketUpdates, firestore.Update{
+				Path:  "claimedAt",
+				Value: time.Now(),
+			})
+			ticketUpdates = append(ticketUpdates, firestore.Update{
+				Path:  "claimedBy",
+				Value: c.ClaimedBy.ID,
+			})
+		} else if c.Status == models.StatusComplete {
+			// Ticket is being marked complete.
+			ticketUpdates = append(ticketUpdates, firestore.Update{
+				Path:  "completedAt",
+				Value: time.Now(),
+			})
+
+			// Remove the ticket from the visible tickets array and move it to the completed tickets array.
+			err := tx.Update(queueRef, []firestore.Update{
+				{Path: "pendingTickets", Value: firestore.ArrayRemove(c.ID)},
+				{Path: "completedTickets", Value: firestore.ArrayUnion(c.ID)},
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		event := newTicketEvent(c.ID, models.TicketEdited, c.ClaimedBy, ticket.Status, c.Status, ticket.Description, description)
+		if err := createTicketEvent(tx, ticketRef, event); err != nil {
+			return err
+		}
+
+		// Edit ticket in collection.
+		return tx.Update(ticketRef, ticketUpdates)
+	})
+	if err != nil {
+		return err
 	}
 
 	if c.Status == models.StatusClaimed {
-		// The ticket is being claimed.
-		ticketUpdates = append(ticketUpdates, firestore.Update{
-			Path:  "claimedAt",
-			Value: time.Now(),
-		})
-		ticketUpdates = append(ticketUpdates, firestore.Update{
-			Path:  "claimedBy",
-			Value: c.ClaimedBy.ID,
-		})
 		notification := models.Notification{
 			Title:     "You've been claimed!",
 			Body:      queue.Course.Code,
 			Timestamp: time.Now(),
 			Type:      models.NotificationClaimed,
 		}
-		err := fr.AddNotification(c.OwnerID, notification)
+		// The owner is read from the ticket, rather than trusted from the request.
+		err := fr.AddNotification(ticket.User.UserID, notification)
 		if err != nil {
 			glog.Warningf("error sending claim notification: %v\n", err)
 		}
-	} else if c.Status == models.StatusComplete {
-		// Ticket is being marked complete.
-		ticketUpdates = append(ticketUpdates, firestore.Update{
-			Path:  "completedAt",
-			Value: time.Now(),
-		})
+	}
+
+	return nil
+}
+
+// DeleteTicket removes a ticket from the queue's pending tickets, records its deletion and deletes it in a single
+// transaction.
+func (fr *FirebaseRepository) DeleteTicket(c *models.DeleteTicketRequest) error {
+	queueRef := fr.firestoreClient.Collection(models.FirestoreQueuesCollection).Doc(c.QueueID)
+	ticketRef := queueRef.Collection(models.FirestoreTicketsCollection).Doc(c.ID)
 
-		// Remove the ticket from the visible tickets array and move it to the completed tickets array.
-		_, err := fr.firestoreClient.Collection(models.FirestoreQueuesCollection).Doc(c.QueueID).Update(firebase.Context, []firestore.Update{
+	return fr.firestoreClient.RunTransaction(firebase.Context, func(ctx context.Context, tx *firestore.Transaction) error {
+		if _, err := tx.Get(queueRef); err != nil {
+			return qerrors.InvalidQueueError
+		}
+		ticketDoc, err := tx.Get(ticketRef)
+		if status.Code(err) == codes.NotFound {
+			return qerrors.TicketNotFoundError
+		} else if err != nil {
+			return err
+		}
+		ticket, err := decodeTicket(ticketDoc)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Update(queueRef, []firestore.Update{
 			{Path: "pendingTickets", Value: firestore.ArrayRemove(c.ID)},
-			{Path: "completedTickets", Value: firestore.ArrayUnion(c.ID)},
 		})
 		if err != nil {
 			return err
 		}
-	}
 
-	// Edit ticket in collection.
-	_, err = fr.firestoreClient.Collection(models.FirestoreQueuesCollection).Doc(c.QueueID).Collection(models.FirestoreTicketsCollection).Doc(c.ID).Update(firebase.Context, ticketUpdates)
-	return err
-}
+		// The ticket's history is kept after it is deleted.
+		event := newTicketEvent(c.ID, models.TicketDeleted, c.DeletedBy, ticket.Status, "", "", "")
+		if err := createTicketEvent(tx, ticketRef, event); err != nil {
+			return err
+		}
 
-func (fr *FirebaseRepository) DeleteTicket(c *models.DeleteTicketRequest) error {
-	_, err := fr.firestoreClient.Collection(models.FirestoreQueuesCollection).Doc(c.QueueID).Update(firebase.Context, []firestore.Update{
-		{Path: "pendingTickets", Value: firestore.ArrayRemove(c.ID)},
+		// Remove ticket from tickets collection.
+		return tx.Delete(ticketRef)
 	})
-	if err != nil {
-		return err
-	}
-
-	// Remove ticket from tickets collection.
-	_, err = fr.firestoreClient.Collection(models.FirestoreQueuesCollection).Doc(c.QueueID).Collection(models.FirestoreTicketsCollection).Doc(c.ID).Delete(firebase.Context)
-	return err
 }
 
 func (fr *FirebaseRepository) MakeAnnouncement(c *models.MakeAnnouncementRequest) error {
@@ -347,8 +667,208 @@ func (fr *FirebaseRepository) GetQueue(ID string) (*models.Queue, error) {
 		return nil, qerrors.QueueNotFoundError
 	}
 
+	return decodeQueue(doc)
+}
+
+// GetTicket gets the Ticket with the given ID from the given queue.
+func (fr *FirebaseRepository) GetTicket(queueID string, ticketID string) (*models.Ticket, error) {
+	doc, err := fr.firestoreClient.Collection(models.FirestoreQueuesCollection).Doc(queueID).Collection(models.FirestoreTicketsCollection).Doc(ticketID).Get(firebase.Context)
+	if status.Code(err) == codes.NotFound {
+		return nil, qerrors.TicketNotFoundError
+	} else if err != nil {
+		return nil, err
+	}
+
+	return decodeTicket(doc)
+}
+
+// GetPendingTickets gets the queue's pending tickets, in order.
+func (fr *FirebaseRepository) GetPendingTickets(queueID string) ([]*models.Ticket, error) {
+	queue, err := fr.GetQueue(queueID)
+	if err != nil {
+		return nil, err
+	}
+
+	ticketsRef := fr.firestoreClient.Collection(models.FirestoreQueuesCollection).Doc(queueID).Collection(models.FirestoreTicketsCollection)
+	refs := make([]*firestore.DocumentRef, 0, len(queue.PendingTickets))
+	for _, id := range queue.PendingTickets {
+		refs = append(refs, ticketsRef.Doc(id))
+	}
+	docs, err := fr.firestoreClient.GetAll(firebase.Context, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	tickets := make([]*models.Ticket, 0, len(docs))
+	for _, doc := range docs {
+		// Skip tickets deleted since the queue was read.
+		if !doc.Exists() {
+			continue
+		}
+		t, err := decodeTicket(doc)
+		if err != nil {
+			return nil, err
+		}
+		tickets = append(tickets, t)
+	}
+	return tickets, nil
+}
+
+// GetTickets gets the queue's tickets created in [from, to), oldest first, whatever their status. A zero from or to
+// leaves that end of the range open.
+func (fr *FirebaseRepository) GetTickets(queueID string, from time.Time, to time.Time) ([]*models.Ticket, error) {
+	query := fr.firestoreClient.Collection(models.FirestoreQueuesCollection).Doc(queueID).
+		Collection(models.FirestoreTicketsCollection).Query
+	if !from.IsZero() {
+		query = query.Where("createdAt", ">=", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("createdAt", "<", to)
+	}
+
+	docs, err := query.OrderBy("createdAt", firestore.Asc).Documents(firebase.Context).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	tickets := make([]*models.Ticket, 0, len(docs))
+	for _, doc := range docs {
+		t, err := decodeTicket(doc)
+		if err != nil {
+			return nil, err
+		}
+		tickets = append(tickets, t)
+	}
+	return tickets, nil
+}
+
+// GetWaitEstimate estimates the wait of each of the queue's pending tickets from its most recently completed tickets.
+func (fr *FirebaseRepository) GetWaitEstimate(queueID string) (*models.WaitEstimate, error) {
+	pending, err := fr.GetPendingTickets(queueID)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := fr.firestoreClient.Collection(models.FirestoreQueuesCollection).Doc(queueID).Collection(models.FirestoreTicketsCollection).
+		OrderBy("completedAt", firestore.Desc).Limit(serviceTimeSampleSize).Documents(firebase.Context).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	completed := make([]*models.Ticket, 0, len(docs))
+	for _, doc := range docs {
+		t, err := decodeTicket(doc)
+		if err != nil {
+			return nil, err
+		}
+		completed = append(completed, t)
+	}
+
+	return estimateWait(queueID, pending, completed, time.Now()), nil
+}
+
+// WatchQueue sends a snapshot of the queue and its tickets on the returned channel whenever either changes, until
+// ctx is done or the queue is deleted. Snapshots that the receiver has not yet read are replaced by newer ones.
+func (fr *FirebaseRepository) WatchQueue(ctx context.Context, queueID string) (<-chan *models.QueueSnapshot, error) {
+	if _, err := fr.GetQueue(queueID); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	snapshots := make(chan *models.QueueSnapshot, 1)
+
+	var lock sync.Mutex
+	var queue *models.Queue
+	var tickets map[string]*models.Ticket
+	publish := func() {
+		// Wait until both listeners have reported.
+		if queue == nil || tickets == nil {
+			return
+		}
+		select {
+		case <-snapshots:
+		default:
+		}
+		snapshots <- &models.QueueSnapshot{Queue: queue, Tickets: tickets}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	// Listen for changes to the queue document.
+	go func() {
+		defer wg.Done()
+		defer cancel()
+
+		it := fr.firestoreClient.Collection(models.FirestoreQueuesCollection).Doc(queueID).Snapshots(ctx)
+		defer it.Stop()
+		for {
+			doc, err := it.Next()
+			if err != nil {
+				if code := status.Code(err); code != codes.DeadlineExceeded && code != codes.Canceled {
+					glog.Warningf("error listening to queue %v: %v\n", queueID, err)
+				}
+				return
+			}
+			if !doc.Exists() {
+				// The queue was deleted.
+				return
+			}
+
+			q, err := decodeQueue(doc)
+			if err != nil {
+				return
+			}
+
+			lock.Lock()
+			queue = q
+			publish()
+			lock.Unlock()
+		}
+	}()
+
+	// Listen for changes to the queue's tickets.
+	go func() {
+		defer wg.Done()
+		defer cancel()
+
+		handleDocs := func(docs []*firestore.DocumentSnapshot) error {
+			newTickets := make(map[string]*models.Ticket, len(docs))
+			for _, doc := range docs {
+				t, err := decodeTicket(doc)
+				if err != nil {
+					return err
+				}
+				newTickets[t.ID] = t
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			tickets = newTickets
+			publish()
+			return nil
+		}
+
+		done := make(chan bool, 1)
+		query := fr.firestoreClient.Collection(models.FirestoreQueuesCollection).Doc(queueID).Collection(models.FirestoreTicketsCollection).Query
+		err := fr.createCollectionInitializer(ctx, query, &done, handleDocs)
+		if err != nil {
+			glog.Warningf("error listening to tickets of queue %v: %v\n", queueID, err)
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(snapshots)
+	}()
+
+	return snapshots, nil
+}
+
+// decodeQueue destructures a queue document.
+func decodeQueue(doc *firestore.DocumentSnapshot) (*models.Queue, error) {
 	var c models.Queue
-	err = mapstructure.Decode(doc.Data(), &c)
+	err := mapstructure.Decode(doc.Data(), &c)
 	if err != nil {
 		glog.Fatalf("Error destructuring queue document: %v", err)
 		return nil, qerrors.QueueNotFoundError
@@ -357,3 +877,185 @@ func (fr *FirebaseRepository) GetQueue(ID string) (*models.Queue, error) {
 	c.ID = doc.Ref.ID
 	return &c, nil
 }
+
+// decodeTicket destructures a ticket document.
+func decodeTicket(doc *firestore.DocumentSnapshot) (*models.Ticket, error) {
+	var t models.Ticket
+	err := mapstructure.Decode(doc.Data(), &t)
+	if err != nil {
+		return nil, err
+	}
+
+	t.ID = doc.Ref.ID
+	return &t, nil
+}
+
+// editedDescription returns the description that an edit gives a ticket.
+func editedDescription(ticket *models.Ticket, c *models.EditTicketRequest) string {
+	if c.KeepDescription {
+		return ticket.Description
+	}
+	return c.Description
+}
+
+// checkNotArchived returns an error if course is archived, since archived courses are read-only.
+func checkNotArchived(course *models.Course) error {
+	if course.IsArchived {
+		return qerrors.CourseArchivedError
+	}
+	return nil
+}
+
+// checkEnrollment returns an error if course only lets enrolled students join its queues and user is not enrolled.
+func checkEnrollment(course *models.Course, user *models.User) error {
+	if course.RestrictToEnrolled && !course.IsEnrolled(user) {
+		return qerrors.NotEnrolledError
+	}
+	return nil
+}
+
+// checkRejoin returns an error if the existing ticket prevents the given user from joining the queue, either because
+// it is still active or because the queue's rejoin cooldown has not elapsed since it was completed.
+func checkRejoin(queue *models.Queue, ticket *models.Ticket, userID string) error {
+	// Check if any ticket violates the queue cooldown.
+	createdByCurrentUser := ticket.User.UserID == userID
+	ticketIsComplete := ticket.Status == models.StatusComplete
+	canNeverRejoin := queue.RejoinCooldown == -1
+	cooldownNotElapsed := time.Now().Sub(ticket.CompletedAt).Minutes() < float64(queue.RejoinCooldown)
+
+	if createdByCurrentUser && ticketIsComplete && (canNeverRejoin || cooldownNotElapsed) {
+		return qerrors.QueueCooldownError
+	}
+
+	// Errors if the current user has an incomplete ticket in the queue
+	if createdByCurrentUser && !ticketIsComplete {
+		return qerrors.ActiveTicketError
+	}
+
+	return nil
+}
+
+// newQueue returns the queue described by c, in the given course.
+func newQueue(c *models.CreateQueueRequest, queueCourse *models.Course) *models.Queue {
+	return &models.Queue{
+		Title:              c.Title,
+		Description:        c.Description,
+		Location:           c.Location,
+		EndTime:            c.EndTime,
+		CourseID:           queueCourse.ID,
+		AllowTicketEditing: c.AllowTicketEditing,
+		ShowMeetingLinks:   c.ShowMeetingLinks,
+		Course:             queueCourse,
+		IsCutOff:           false,
+		FaceMaskPolicy:     c.FaceMaskPolicy,
+		RejoinCooldown:     c.RejoinCooldown,
+		OrderingPolicy:     c.OrderingPolicy,
+		Categories:         c.Categories,
+	}
+}
+
+// queueDocument returns the Firestore document for a new queue.
+func queueDocument(queue *models.Queue) map[string]interface{} {
+	return map[string]interface{}{
+		"title":       queue.Title,
+		"description": queue.Description,
+		"location":    queue.Location,
+		"endTime":     queue.EndTime,
+		"courseID":    queue.CourseID,
+		"course": map[string]interface{}{
+			"id":    queue.Course.ID,
+			"title": queue.Course.Title,
+			"code":  queue.Course.Code,
+		},
+		"completedTickets":   []string{},
+		"pendingTickets":     []string{},
+		"isCutOff":           queue.IsCutOff,
+		"allowTicketEditing": queue.AllowTicketEditing,
+		"showMeetingLinks":   queue.ShowMeetingLinks,
+		"faceMaskPolicy":     queue.FaceMaskPolicy,
+		"rejoinCooldown":     queue.RejoinCooldown,
+		"orderingPolicy":     queue.OrderingPolicy,
+		"categories":         queue.Categories,
+	}
+}
+
+// hasEnded returns whether the queue is open even though its end time is before now. Queues without an end time never
+// end.
+func hasEnded(queue *models.Queue, now time.Time) bool {
+	return !queue.IsCutOff && !queue.EndTime.IsZero() && queue.EndTime.Before(now)
+}
+
+// isClosed returns whether the queue no longer accepts new tickets, either because it was cut off or because its end
+// time has passed.
+func isClosed(queue *models.Queue, now time.Time) bool {
+	return queue.IsCutOff || hasEnded(queue, now)
+}
+
+// validCategories returns an error if a queue's categories contain a blank or repeated category.
+func validCategories(categories []string) error {
+	seen := make(map[string]bool, len(categories))
+	for _, category := range categories {
+		if strings.TrimSpace(category) == "" || seen[category] {
+			return qerrors.InvalidCategoriesError
+		}
+		seen[category] = true
+	}
+	return nil
+}
+
+// checkCategory returns an error if category may not be given to a ticket in the queue. Queues with categories require
+// one of them, and queues without categories accept none.
+func checkCategory(queue *models.Queue, category string) error {
+	if len(queue.Categories) == 0 {
+		if category != "" {
+			return qerrors.InvalidCategoryError
+		}
+		return nil
+	}
+
+	if category == "" {
+		return qerrors.MissingCategoryError
+	}
+	for _, c := range queue.Categories {
+		if c == category {
+			return nil
+		}
+	}
+	return qerrors.InvalidCategoryError
+}
+
+// nextClaimableTicket returns the first of the pending tickets that is waiting to be helped and, if category is not
+// empty, is in that category.
+func nextClaimableTicket(pending []*models.Ticket, category string) *models.Ticket {
+	for _, t := range pending {
+		if t.Status != models.StatusWaiting && t.Status != models.StatusReturned {
+			continue
+		}
+		if category != "" && t.Category != category {
+			continue
+		}
+		return t
+	}
+	return nil
+}
+
+// newShuffleSeed returns a cryptographically random seed for shuffleTickets.
+func newShuffleSeed() int64 {
+	var b [8]byte
+	if _, err := cryptorand.Read(b[:]); err != nil {
+		// Fall back to the time, which is still recorded alongside the shuffle.
+		return time.Now().UnixNano()
+	}
+	return int64(binary.LittleEndian.Uint64(b[:]))
+}
+
+// shuffleTickets returns a copy of tickets shuffled by a math/rand source with the given seed. The same seed and input
+// always produce the same order.
+func shuffleTickets(tickets []string, seed int64) []string {
+	shuffled := append([]string{}, tickets...)
+	r := rand.New(rand.NewSource(seed))
+	r.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	return shuffled
+}
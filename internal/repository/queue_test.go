@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"os"
+	"sync"
+	"testing"
+
+	"signmeup/internal/config"
+	"signmeup/internal/firebase"
+	"signmeup/internal/models"
+	"signmeup/internal/qerrors"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// concurrentCalls is the number of goroutines that race each operation.
+const concurrentCalls = 50
+
+// newEmulatorRepository creates a repository backed by the Firestore emulator, skipping the test if the emulator is not
+// running. Only the operations that do not use Firebase Authentication work with it.
+func newEmulatorRepository(t *testing.T) *FirebaseRepository {
+	t.Helper()
+
+	if os.Getenv("FIRESTORE_EMULATOR_HOST") == "" {
+		t.Skip("FIRESTORE_EMULATOR_HOST is not set")
+	}
+	// The client connects to the emulator instead of Firestore when FIRESTORE_EMULATOR_HOST is set.
+	client, err := firestore.NewClient(firebase.Context, "signmeup-test")
+	if err != nil {
+		t.Fatalf("creating Firestore client: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return &FirebaseRepository{
+		cfg:             config.DefaultDevelopmentConfig(),
+		firestoreClient: client,
+		profilesLock:    &sync.RWMutex{},
+		profiles:        make(map[string]*models.Profile),
+	}
+}
+
+// newEmulatorQueue creates a course with an open queue in the Firestore emulator, and a student and a staff member of
+// the course.
+func newEmulatorQueue(t *testing.T) (repo *FirebaseRepository, queue *models.Queue, student *models.User, staff *models.User) {
+	t.Helper()
+
+	repo = newEmulatorRepository(t)
+	course, err := repo.CreateCourse(&models.CreateCourseRequest{Title: "Intro", Code: "cs0150", Term: "fall"})
+	if err != nil {
+		t.Fatalf("creating course: %v", err)
+	}
+	queue, err = repo.CreateQueue(&models.CreateQueueRequest{Title: "Hours", CourseID: course.ID})
+	if err != nil {
+		t.Fatalf("creating queue: %v", err)
+	}
+
+	// Users are not stored with their tickets, so they do not need to exist in Firebase Authentication.
+	student = &models.User{
+		ID:      "student-" + course.ID,
+		Profile: &models.Profile{Email: "student@brown.edu", DisplayName: "Student"},
+	}
+	staff = &models.User{
+		ID: "staff-" + course.ID,
+		Profile: &models.Profile{
+			Email:             "staff@brown.edu",
+			DisplayName:       "Staff",
+			CoursePermissions: map[string]models.CoursePermission{course.ID: models.CourseStaff},
+		},
+	}
+	return repo, queue, student, staff
+}
+
+// race calls f from concurrentCalls goroutines at once, and returns the number of calls that succeeded.
+func race(f func() error) int {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	start := make(chan struct{})
+	succeeded := 0
+
+	for i := 0; i < concurrentCalls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if err := f(); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	return succeeded
+}
+
+// unexpectedError returns true if err is neither nil, nor the error that the losers of a race expect, nor a Firestore
+// transaction that gave up after too much contention.
+func unexpectedError(err error, expected error) bool {
+	return err != nil && err != expected && status.Code(err) != codes.Aborted
+}
+
+// createTestTicket creates a ticket in queue for student.
+func createTestTicket(t *testing.T, repo Repository, queue *models.Queue, student *models.User) *models.Ticket {
+	t.Helper()
+
+	ticket, err := repo.CreateTicket(&models.CreateTicketRequest{QueueID: queue.ID, CreatedBy: student, Description: "help"})
+	if err != nil {
+		t.Fatalf("creating ticket: %v", err)
+	}
+	return ticket
+}
+
+func testCreateTicketConcurrently(t *testing.T, repo Repository, queue *models.Queue, student *models.User) {
+	created := race(func() error {
+		_, err := repo.CreateTicket(&models.CreateTicketRequest{QueueID: queue.ID, CreatedBy: student, Description: "help"})
+		if unexpectedError(err, qerrors.ActiveTicketError) {
+			t.Errorf("unexpected error creating ticket: %v", err)
+		}
+		return err
+	})
+	if created != 1 {
+		t.Errorf("created %d tickets, want 1", created)
+	}
+
+	queue, err := repo.GetQueue(queue.ID)
+	if err != nil {
+		t.Fatalf("getting queue: %v", err)
+	}
+	if len(queue.PendingTickets) != 1 {
+		t.Errorf("queue has %d pending tickets, want 1", len(queue.PendingTickets))
+	}
+	tickets, err := repo.GetPendingTickets(queue.ID)
+	if err != nil {
+		t.Fatalf("getting pending tickets: %v", err)
+	}
+	if len(tickets) != 1 {
+		t.Errorf("got %d pending tickets, want 1", len(tickets))
+	}
+}
+
+func testClaimNextTicketConcurrently(t *testing.T, repo Repository, queue *models.Queue, student *models.User, staff *models.User) {
+	ticket := createTestTicket(t, repo, queue, student)
+
+	claimed := race(func() error {
+		_, err := repo.ClaimNextTicket(&models.ClaimNextTicketRequest{QueueID: queue.ID, ClaimedBy: staff})
+		if unexpectedError(err, qerrors.NoWaitingTicketsError) {
+			t.Errorf("unexpected error claiming ticket: %v", err)
+		}
+		return err
+	})
+	if claimed != 1 {
+		t.Errorf("claimed the ticket %d times, want 1", claimed)
+	}
+
+	ticket, err := repo.GetTicket(queue.ID, ticket.ID)
+	if err != nil {
+		t.Fatalf("getting ticket: %v", err)
+	}
+	if ticket.Status != models.StatusClaimed || ticket.ClaimedBy != staff.ID {
+		t.Errorf("ticket is %s by %q, want %s by %q", ticket.Status, ticket.ClaimedBy, models.StatusClaimed, staff.ID)
+	}
+}
+
+func testCompleteTicketConcurrently(t *testing.T, repo Repository, queue *models.Queue, student *models.User, staff *models.User) {
+	ticket := createTestTicket(t, repo, queue, student)
+
+	completed := race(func() error {
+		return repo.EditTicket(&models.EditTicketRequest{
+			ID:              ticket.ID,
+			QueueID:         queue.ID,
+			Status:          models.StatusComplete,
+			KeepDescription: true,
+			ClaimedBy:       staff,
+		})
+	})
+	if completed != 1 {
+		t.Errorf("completed the ticket %d times, want 1", completed)
+	}
+
+	queue, err := repo.GetQueue(queue.ID)
+	if err != nil {
+		t.Fatalf("getting queue: %v", err)
+	}
+	if len(queue.PendingTickets) != 0 {
+		t.Errorf("queue has %d pending tickets, want 0", len(queue.PendingTickets))
+	}
+	if len(queue.CompletedTickets) != 1 || queue.CompletedTickets[0] != ticket.ID {
+		t.Errorf("queue has completed tickets %v, want [%s]", queue.CompletedTickets, ticket.ID)
+	}
+}
+
+func testDeleteTicketConcurrently(t *testing.T, repo Repository, queue *models.Queue, student *models.User) {
+	ticket := createTestTicket(t, repo, queue, student)
+
+	deleted := race(func() error {
+		err := repo.DeleteTicket(&models.DeleteTicketRequest{ID: ticket.ID, QueueID: queue.ID, DeletedBy: student})
+		if unexpectedError(err, qerrors.TicketNotFoundError) {
+			t.Errorf("unexpected error deleting ticket: %v", err)
+		}
+		return err
+	})
+	if deleted != 1 {
+		t.Errorf("deleted the ticket %d times, want 1", deleted)
+	}
+
+	queue, err := repo.GetQueue(queue.ID)
+	if err != nil {
+		t.Fatalf("getting queue: %v", err)
+	}
+	if len(queue.PendingTickets) != 0 {
+		t.Errorf("queue has %d pending tickets, want 0", len(queue.PendingTickets))
+	}
+	if _, err := repo.GetTicket(queue.ID, ticket.ID); err != qerrors.TicketNotFoundError {
+		t.Errorf("getting deleted ticket returned %v, want %v", err, qerrors.TicketNotFoundError)
+	}
+}
+
+func TestFirebaseCreateTicketConcurrently(t *testing.T) {
+	repo, queue, student, _ := newEmulatorQueue(t)
+	testCreateTicketConcurrently(t, repo, queue, student)
+}
+
+func TestFirebaseClaimNextTicketConcurrently(t *testing.T) {
+	repo, queue, student, staff := newEmulatorQueue(t)
+	testClaimNextTicketConcurrently(t, repo, queue, student, staff)
+}
+
+func TestFirebaseCompleteTicketConcurrently(t *testing.T) {
+	repo, queue, student, staff := newEmulatorQueue(t)
+	testCompleteTicketConcurrently(t, repo, queue, student, staff)
+}
+
+func TestFirebaseDeleteTicketConcurrently(t *testing.T) {
+	repo, queue, student, _ := newEmulatorQueue(t)
+	testDeleteTicketConcurrently(t, repo, queue, student)
+}
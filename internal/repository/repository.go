@@ -1,31 +1,139 @@
 package repository
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"sync"
+	"time"
 
+	"signmeup/internal/config"
 	"signmeup/internal/firebase"
 	"signmeup/internal/models"
 
+	firebaseSDK "firebase.google.com/go"
 	firebaseAuth "firebase.google.com/go/auth"
 
 	"cloud.google.com/go/firestore"
 )
 
-var Repository *FirebaseRepository
+// Repository is the set of operations the server needs from its backing store. It is implemented by
+// FirebaseRepository in production, and by MemoryRepository for tests and local development.
+type Repository interface {
+	CourseRepository
+	ScheduleRepository
+	QueueTemplateRepository
+	QueueRepository
+	UserRepository
+	NotificationRepository
+	AuditRepository
+	InviteRepository
+}
 
-func init() {
-	var err error
-	Repository, err = NewFirebaseRepository()
-	if err != nil {
-		log.Panicf("Error creating repository: %v\n", err)
-	}
+// CourseRepository encapsulates operations on courses and their permissions.
+type CourseRepository interface {
+	GetCourseByID(ID string) (*models.Course, error)
+	GetCourseByInfo(code string, term string) (*models.Course, error)
+	CreateCourse(c *models.CreateCourseRequest) (*models.Course, error)
+	DeleteCourse(c *models.DeleteCourseRequest) error
+	EditCourse(c *models.EditCourseRequest) error
+	AddPermission(c *models.AddCoursePermissionRequest) error
+	RemovePermission(c *models.RemoveCoursePermissionRequest) error
+	BulkUpload(c *models.BulkUploadRequest) (*models.BulkUploadReport, error)
+	ImportRoster(c *models.ImportRosterRequest) (*models.RosterImportResult, error)
+	GetCoursesByTerm(term string) ([]*models.Course, error)
+	ListCourses(c *models.ListCoursesRequest) (*models.CoursePage, error)
+	SetCourseArchived(c *models.SetCourseArchivedRequest) error
+	RolloverTerm(c *models.RolloverTermRequest) (*models.RolloverReport, error)
+	ArchiveCoursesByTerm(c *models.ArchiveCoursesByTermRequest) (int, error)
+	DeleteCoursesByTerm(term string) error
+	GetCourseAnalytics(c *models.GetCourseAnalyticsRequest) (*models.CourseAnalytics, error)
+	GetStudentVisits(courseID string, userID string) ([]*models.StudentVisit, error)
+}
+
+// ScheduleRepository encapsulates operations on courses' recurring office hours schedules.
+type ScheduleRepository interface {
+	GetSchedules(courseID string) ([]*models.Schedule, error)
+	GetAllSchedules() ([]*models.Schedule, error)
+	CreateSchedule(c *models.CreateScheduleRequest) (*models.Schedule, error)
+	EditSchedule(c *models.EditScheduleRequest) error
+	DeleteSchedule(c *models.DeleteScheduleRequest) error
+	StartScheduledSession(courseID string, scheduleID string, now time.Time) (*models.Queue, error)
+}
+
+// QueueTemplateRepository encapsulates operations on courses' queue templates.
+type QueueTemplateRepository interface {
+	GetQueueTemplates(courseID string) ([]*models.QueueTemplate, error)
+	GetQueueTemplate(courseID string, templateID string) (*models.QueueTemplate, error)
+	CreateQueueTemplate(c *models.CreateQueueTemplateRequest) (*models.QueueTemplate, error)
+	EditQueueTemplate(c *models.EditQueueTemplateRequest) error
+	DeleteQueueTemplate(c *models.DeleteQueueTemplateRequest) error
+}
+
+// QueueRepository encapsulates operations on queues and their tickets.
+type QueueRepository interface {
+	GetQueue(ID string) (*models.Queue, error)
+	CreateQueue(c *models.CreateQueueRequest) (*models.Queue, error)
+	EditQueue(c *models.EditQueueRequest) error
+	DeleteQueue(c *models.DeleteQueueRequest) error
+	CutoffQueue(c *models.CutoffQueueRequest) error
+	GetEndedQueues(now time.Time) ([]*models.Queue, error)
+	GetCourseQueues(courseID string) ([]*models.Queue, error)
+	ShuffleQueue(c *models.ShuffleQueueRequest) (*models.ShuffleRecord, error)
+	GetShuffleRecords(queueID string) ([]*models.ShuffleRecord, error)
+	MakeAnnouncement(c *models.MakeAnnouncementRequest) error
+	WatchQueue(ctx context.Context, queueID string) (<-chan *models.QueueSnapshot, error)
 
-	log.Printf("✅ Successfully created Firebase repository client")
+	GetTicket(queueID string, ticketID string) (*models.Ticket, error)
+	GetPendingTickets(queueID string) ([]*models.Ticket, error)
+	GetTickets(queueID string, from time.Time, to time.Time) ([]*models.Ticket, error)
+	GetTicketEvents(queueID string, ticketID string) ([]*models.TicketEvent, error)
+	GetWaitEstimate(queueID string) (*models.WaitEstimate, error)
+	CreateTicket(c *models.CreateTicketRequest) (*models.Ticket, error)
+	ClaimNextTicket(c *models.ClaimNextTicketRequest) (*models.Ticket, error)
+	EditTicket(c *models.EditTicketRequest) error
+	DeleteTicket(c *models.DeleteTicketRequest) error
 }
 
+// UserRepository encapsulates operations on users and their profiles.
+type UserRepository interface {
+	GetUserByID(id string) (*models.User, error)
+	GetUserByEmail(email string) (*models.User, error)
+	GetIDByEmail(email string) (string, error)
+	UpdateUser(r *models.UpdateUserRequest) error
+	MakeAdminByEmail(u *models.MakeAdminByEmailRequest) error
+	Count() int
+	List() ([]*models.User, error)
+	Create(user *models.CreateUserRequest) (*models.User, error)
+	Delete(id string) error
+	AddFavoriteCourse(userID string, courseID string) error
+	RemoveFavoriteCourse(userID string, courseID string) error
+}
+
+// NotificationRepository encapsulates operations on a user's notifications.
+type NotificationRepository interface {
+	AddNotification(userID string, notification models.Notification) error
+	ClearNotification(c *models.ClearNotificationRequest) error
+	ClearAllNotifications(c *models.ClearAllNotificationsRequest) error
+}
+
+// AuditRepository encapsulates operations on the audit log of changes to courses and access.
+type AuditRepository interface {
+	AddAuditLog(entry *models.AuditLogEntry) error
+	GetAuditLogs(c *models.GetAuditLogsRequest) (*models.AuditLogPage, error)
+}
+
+// InviteRepository encapsulates operations on pending course invites.
+type InviteRepository interface {
+	GetCourseInvites(courseID string) ([]*models.CourseInvite, error)
+	GetInvitesByEmail(email string) ([]*models.CourseInvite, error)
+	EditInvite(c *models.EditInviteRequest) error
+	RevokeInvite(c *models.RevokeInviteRequest) error
+}
+
+var _ Repository = (*FirebaseRepository)(nil)
+
 type FirebaseRepository struct {
+	cfg             *config.ServerConfig
 	authClient      *firebaseAuth.Client
 	firestoreClient *firestore.Client
 
@@ -33,19 +141,20 @@ type FirebaseRepository struct {
 	profiles     map[string]*models.Profile
 }
 
-func NewFirebaseRepository() (*FirebaseRepository, error) {
+func NewFirebaseRepository(cfg *config.ServerConfig, app *firebaseSDK.App) (*FirebaseRepository, error) {
 	fr := &FirebaseRepository{
+		cfg:          cfg,
 		profilesLock: &sync.RWMutex{},
 		profiles:     make(map[string]*models.Profile),
 	}
 
-	authClient, err := firebase.App.Auth(firebase.Context)
+	authClient, err := app.Auth(firebase.Context)
 	if err != nil {
 		return nil, fmt.Errorf("Auth client error: %v\n", err)
 	}
 	fr.authClient = authClient
 
-	firestoreClient, err := firebase.App.Firestore(firebase.Context)
+	firestoreClient, err := app.Firestore(firebase.Context)
 	if err != nil {
 		return nil, fmt.Errorf("Firestore client error: %v\n", err)
 	}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"fmt"
+	"signmeup/internal/models"
+	"signmeup/internal/qerrors"
+
+	"github.com/golang/glog"
+)
+
+// rolloverTerm clones the courses of a term into another using the given repository, as described by a
+// RolloverTermRequest. A course whose code is already taken in the new term is reported as a conflict and left alone,
+// so a rollover can be run again after fixing the courses it could not clone. Schedules are not cloned, since their
+// times, staff and exception dates belong to the old term; they are left behind with the archived course. The rollover
+// is recorded in the audit log, as is each course it archives and permission it copies.
+func rolloverTerm(r Repository, c *models.RolloverTermRequest) (*models.RolloverReport, error) {
+	if c.FromTerm == "" || c.ToTerm == "" || c.FromTerm == c.ToTerm {
+		return nil, qerrors.InvalidRolloverError
+	}
+
+	courses, err := r.GetCoursesByTerm(c.FromTerm)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.RolloverReport{
+		FromTerm: c.FromTerm,
+		ToTerm:   c.ToTerm,
+		Courses:  make([]*models.RolloverCourseReport, 0, len(courses)),
+	}
+	cloned := 0
+	for _, course := range courses {
+		courseReport := rolloverCourse(r, c, course)
+		if courseReport.Outcome == models.RolloverCloned {
+			cloned++
+		}
+		report.Courses = append(report.Courses, courseReport)
+	}
+
+	entry := newAuditLogEntry(models.AuditRolloverTerm, c.Actor, "", c.ToTerm, map[string]models.AuditChange{
+		"fromTerm": {New: c.FromTerm},
+		"courses":  {New: cloned},
+	})
+	if err := r.AddAuditLog(entry); err != nil {
+		glog.Errorf("%v: %+v\n", err, entry)
+	}
+	return report, nil
+}
+
+// rolloverCourse clones a course into the new term of a rollover and archives it. If the course cannot be fully cloned,
+// its partial clone is deleted, so that it is not reported as a conflict when the rollover is run again.
+func rolloverCourse(r Repository, c *models.RolloverTermRequest, course *models.Course) *models.RolloverCourseReport {
+	report := &models.RolloverCourseReport{
+		Code:        course.Code,
+		OldCourseID: course.ID,
+	}
+	fail := func(err error) *models.RolloverCourseReport {
+		report.Outcome = models.RolloverFailed
+		report.Reason = err.Error()
+		return report
+	}
+
+	existing, err := r.GetCourseByInfo(course.Code, c.ToTerm)
+	if err == nil {
+		report.Outcome = models.RolloverConflict
+		report.NewCourseID = existing.ID
+		report.Reason = fmt.Sprintf("%s already exists in %s", course.Code, c.ToTerm)
+		return report
+	} else if err != qerrors.CourseNotFoundError {
+		return fail(err)
+	}
+
+	clone, err := r.CreateCourse(&models.CreateCourseRequest{
+		Title:              course.Title,
+		Code:               course.Code,
+		Term:               c.ToTerm,
+		RestrictToEnrolled: course.RestrictToEnrolled,
+		CreatedBy:          c.Actor,
+	})
+	if err != nil {
+		return fail(err)
+	}
+	report.NewCourseID = clone.ID
+	discard := func(err error) *models.RolloverCourseReport {
+		deleteErr := r.DeleteCourse(&models.DeleteCourseRequest{CourseID: clone.ID, Actor: c.Actor})
+		if deleteErr != nil {
+			glog.Errorf("failed to delete partial clone %s of course %s: %v\n", clone.ID, course.ID, deleteErr)
+			return fail(fmt.Errorf("%v; the partial clone could not be deleted: %v", err, deleteErr))
+		}
+		report.NewCourseID, report.Templates, report.Staff = "", 0, 0
+		return fail(err)
+	}
+
+	templates, err := r.GetQueueTemplates(course.ID)
+	if err != nil {
+		return discard(err)
+	}
+	for _, template := range templates {
+		req := template.CloneRequest(clone.ID)
+		if _, err := r.CreateQueueTemplate(&req); err != nil {
+			return discard(err)
+		}
+		report.Templates++
+	}
+
+	if c.CopyStaff {
+		for userID, permission := range course.CoursePermissions {
+			if permission != models.CourseAdmin && permission != models.CourseStaff {
+				continue
+			}
+
+			user, err := r.GetUserByID(userID)
+			if err != nil {
+				// The user's account has been deleted since they were given the permission.
+				glog.Warningf("not copying permission of missing user %s in course %s: %v\n", userID, course.ID, err)
+				continue
+			}
+			err = r.AddPermission(&models.AddCoursePermissionRequest{
+				CourseID:   clone.ID,
+				Email:      user.Email,
+				Permission: string(permission),
+				Actor:      c.Actor,
+			})
+			if err != nil {
+				return discard(err)
+			}
+			report.Staff++
+		}
+	}
+
+	err = r.SetCourseArchived(&models.SetCourseArchivedRequest{CourseID: course.ID, Archived: true, Actor: c.Actor})
+	if err != nil {
+		return discard(err)
+	}
+
+	report.Outcome = models.RolloverCloned
+	return report
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"signmeup/internal/models"
+)
+
+// failingPermissionRepository is a memory repository that cannot add course permissions until it is fixed.
+type failingPermissionRepository struct {
+	*MemoryRepository
+	fixed bool
+}
+
+func (fr *failingPermissionRepository) AddPermission(c *models.AddCoursePermissionRequest) error {
+	if !fr.fixed {
+		return errors.New("permissions are unavailable")
+	}
+	return fr.MemoryRepository.AddPermission(c)
+}
+
+func TestRolloverDeletesPartialClone(t *testing.T) {
+	repo, queue, _, staff := newTestQueue(t)
+	_, err := repo.CreateQueueTemplate(&models.CreateQueueTemplateRequest{CourseID: queue.CourseID, Name: "Hours", Title: "Hours"})
+	if err != nil {
+		t.Fatalf("creating template: %v", err)
+	}
+	r := &failingPermissionRepository{MemoryRepository: repo}
+	req := &models.RolloverTermRequest{FromTerm: "fall", ToTerm: "spring", CopyStaff: true, Actor: staff}
+
+	report, err := rolloverTerm(r, req)
+	if err != nil {
+		t.Fatalf("rolling over: %v", err)
+	}
+	if got := report.Courses[0]; got.Outcome != models.RolloverFailed || got.NewCourseID != "" {
+		t.Errorf("got outcome %s with new course %q, want %s without one", got.Outcome, got.NewCourseID, models.RolloverFailed)
+	}
+	if courses, _ := repo.GetCoursesByTerm("spring"); len(courses) != 0 {
+		t.Errorf("failed rollover left %d courses in the new term, want 0", len(courses))
+	}
+
+	// Running the rollover again once the failure is fixed clones the course, rather than reporting a conflict.
+	r.fixed = true
+	report, err = rolloverTerm(r, req)
+	if err != nil {
+		t.Fatalf("rolling over again: %v", err)
+	}
+	if got := report.Courses[0]; got.Outcome != models.RolloverCloned || got.Templates != 1 || got.Staff != 1 {
+		t.Errorf("got outcome %s with %d templates and %d staff, want %s with 1 of each",
+			got.Outcome, got.Templates, got.Staff, models.RolloverCloned)
+	}
+}
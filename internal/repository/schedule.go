@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"signmeup/internal/firebase"
+	"signmeup/internal/models"
+	"signmeup/internal/qerrors"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"cloud.google.com/go/firestore"
+	"github.com/mitchellh/mapstructure"
+)
+
+// GetSchedules gets the recurring sessions of a course.
+func (fr *FirebaseRepository) GetSchedules(courseID string) ([]*models.Schedule, error) {
+	docs, err := fr.firestoreClient.Collection(models.FirestoreCoursesCollection).Doc(courseID).
+		Collection(models.FirestoreSchedulesCollection).Documents(firebase.Context).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeSchedules(docs)
+}
+
+// GetAllSchedules gets the recurring sessions of every course.
+func (fr *FirebaseRepository) GetAllSchedules() ([]*models.Schedule, error) {
+	docs, err := fr.firestoreClient.CollectionGroup(models.FirestoreSchedulesCollection).Documents(firebase.Context).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeSchedules(docs)
+}
+
+func (fr *FirebaseRepository) CreateSchedule(c *models.CreateScheduleRequest) (*models.Schedule, error) {
+	if _, err := fr.GetCourseByID(c.CourseID); err != nil {
+		return nil, err
+	}
+
+	schedule := &models.Schedule{
+		CourseID:       c.CourseID,
+		Title:          c.Title,
+		Description:    c.Description,
+		Location:       c.Location,
+		Weekday:        c.Weekday,
+		StartTime:      c.StartTime,
+		EndTime:        c.EndTime,
+		TimeZone:       c.TimeZone,
+		FaceMaskPolicy: c.FaceMaskPolicy,
+		RejoinCooldown: c.RejoinCooldown,
+		Staff:          c.Staff,
+		ExceptionDates: c.ExceptionDates,
+	}
+	if err := validSchedule(schedule); err != nil {
+		return nil, err
+	}
+
+	ref, _, err := fr.firestoreClient.Collection(models.FirestoreCoursesCollection).Doc(c.CourseID).
+		Collection(models.FirestoreSchedulesCollection).Add(firebase.Context, map[string]interface{}{
+		"courseID":       schedule.CourseID,
+		"title":          schedule.Title,
+		"description":    schedule.Description,
+		"location":       schedule.Location,
+		"weekday":        schedule.Weekday,
+		"startTime":      schedule.StartTime,
+		"endTime":        schedule.EndTime,
+		"timeZone":       schedule.TimeZone,
+		"faceMaskPolicy": schedule.FaceMaskPolicy,
+		"rejoinCooldown": schedule.RejoinCooldown,
+		"staff":          schedule.Staff,
+		"exceptionDates": schedule.ExceptionDates,
+		"lastOccurrence": schedule.LastOccurrence,
+		"lastQueueID":    schedule.LastQueueID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating schedule: %v", err)
+	}
+
+	schedule.ID = ref.ID
+	return schedule, nil
+}
+
+func (fr *FirebaseRepository) EditSchedule(c *models.EditScheduleRequest) error {
+	schedule := &models.Schedule{
+		Weekday:        c.Weekday,
+		StartTime:      c.StartTime,
+		EndTime:        c.EndTime,
+		TimeZone:       c.TimeZone,
+		ExceptionDates: c.ExceptionDates,
+	}
+	if err := validSchedule(schedule); err != nil {
+		return err
+	}
+
+	_, err := fr.firestoreClient.Collection(models.FirestoreCoursesCollection).Doc(c.CourseID).
+		Collection(models.FirestoreSchedulesCollection).Doc(c.ScheduleID).Update(firebase.Context, []firestore.Update{
+		{Path: "title", Value: c.Title},
+		{Path: "description", Value: c.Description},
+		{Path: "location", Value: c.Location},
+		{Path: "weekday", Value: c.Weekday},
+		{Path: "startTime", Value: c.StartTime},
+		{Path: "endTime", Value: c.EndTime},
+		{Path: "timeZone", Value: c.TimeZone},
+		{Path: "faceMaskPolicy", Value: c.FaceMaskPolicy},
+		{Path: "rejoinCooldown", Value: c.RejoinCooldown},
+		{Path: "staff", Value: c.Staff},
+		{Path: "exceptionDates", Value: c.ExceptionDates},
+	})
+	if status.Code(err) == codes.NotFound {
+		return qerrors.ScheduleNotFoundError
+	}
+	return err
+}
+
+func (fr *FirebaseRepository) DeleteSchedule(c *models.DeleteScheduleRequest) error {
+	_, err := fr.firestoreClient.Collection(models.FirestoreCoursesCollection).Doc(c.CourseID).
+		Collection(models.FirestoreSchedulesCollection).Doc(c.ScheduleID).Delete(firebase.Context)
+	return err
+}
+
+// StartScheduledSession creates the queue for the session of a schedule in progress at now. The schedule records the
+// session it last created a queue for in the same transaction, so that when several instances try to start the same
+// session, only one queue is created and the others get qerrors.SessionAlreadyStartedError.
+func (fr *FirebaseRepository) StartScheduledSession(courseID string, scheduleID string, now time.Time) (*models.Queue, error) {
+	courseRef := fr.firestoreClient.Collection(models.FirestoreCoursesCollection).Doc(courseID)
+	scheduleRef := courseRef.Collection(models.FirestoreSchedulesCollection).Doc(scheduleID)
+	queueRef := fr.firestoreClient.Collection(models.FirestoreQueuesCollection).NewDoc()
+
+	var queue *models.Queue
+	err := fr.firestoreClient.RunTransaction(firebase.Context, func(ctx context.Context, tx *firestore.Transaction) error {
+		scheduleDoc, err := tx.Get(scheduleRef)
+		if status.Code(err) == codes.NotFound {
+			return qerrors.ScheduleNotFoundError
+		} else if err != nil {
+			return err
+		}
+		schedule, err := decodeSchedule(scheduleDoc)
+		if err != nil {
+			return err
+		}
+
+		courseDoc, err := tx.Get(courseRef)
+		if status.Code(err) == codes.NotFound {
+			return qerrors.CourseNotFoundError
+		} else if err != nil {
+			return err
+		}
+		var course models.Course
+		if err := mapstructure.Decode(courseDoc.Data(), &course); err != nil {
+			return err
+		}
+		course.ID = courseDoc.Ref.ID
+
+		date, _, end, ok := schedule.Occurrence(now)
+		if !ok {
+			return qerrors.NoScheduledSessionError
+		}
+		if schedule.LastOccurrence == date {
+			return qerrors.SessionAlreadyStartedError
+		}
+		if err := checkNotArchived(&course); err != nil {
+			return err
+		}
+
+		queue = newQueue(scheduledQueueRequest(schedule, end), &course)
+		queue.ID = queueRef.ID
+		if err := tx.Create(queueRef, queueDocument(queue)); err != nil {
+			return fmt.Errorf("error creating queue: %v", err)
+		}
+
+		return tx.Update(scheduleRef, []firestore.Update{
+			{Path: "lastOccurrence", Value: date},
+			{Path: "lastQueueID", Value: queue.ID},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return queue, nil
+}
+
+// decodeSchedule destructures a schedule document.
+func decodeSchedule(doc *firestore.DocumentSnapshot) (*models.Schedule, error) {
+	var s models.Schedule
+	err := mapstructure.Decode(doc.Data(), &s)
+	if err != nil {
+		return nil, err
+	}
+
+	s.ID = doc.Ref.ID
+	return &s, nil
+}
+
+// decodeSchedules destructures a list of schedule documents.
+func decodeSchedules(docs []*firestore.DocumentSnapshot) ([]*models.Schedule, error) {
+	schedules := make([]*models.Schedule, 0, len(docs))
+	for _, doc := range docs {
+		s, err := decodeSchedule(doc)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, nil
+}
+
+// validSchedule returns an error if a schedule's weekday, times, time zone or exception dates are malformed, or if its
+// sessions would end before they start.
+func validSchedule(s *models.Schedule) error {
+	if s.Weekday < time.Sunday || s.Weekday > time.Saturday {
+		return qerrors.InvalidScheduleError
+	}
+	if _, err := time.LoadLocation(s.TimeZone); err != nil || s.TimeZone == "" {
+		return qerrors.InvalidScheduleError
+	}
+
+	start, err := time.Parse(models.ScheduleTimeLayout, s.StartTime)
+	if err != nil {
+		return qerrors.InvalidScheduleError
+	}
+	end, err := time.Parse(models.ScheduleTimeLayout, s.EndTime)
+	if err != nil || !end.After(start) {
+		return qerrors.InvalidScheduleError
+	}
+
+	for _, date := range s.ExceptionDates {
+		if _, err := time.Parse(models.ScheduleDateLayout, date); err != nil {
+			return qerrors.InvalidScheduleError
+		}
+	}
+	return nil
+}
+
+// scheduledQueueRequest describes the queue for a session of a schedule that ends at end.
+func scheduledQueueRequest(s *models.Schedule, end time.Time) *models.CreateQueueRequest {
+	return &models.CreateQueueRequest{
+		Title:          s.Title,
+		Description:    s.Description,
+		Location:       s.Location,
+		EndTime:        end,
+		CourseID:       s.CourseID,
+		FaceMaskPolicy: s.FaceMaskPolicy,
+		RejoinCooldown: s.RejoinCooldown,
+	}
+}
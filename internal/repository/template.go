@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"fmt"
+	"signmeup/internal/firebase"
+	"signmeup/internal/models"
+	"signmeup/internal/qerrors"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"cloud.google.com/go/firestore"
+	"github.com/mitchellh/mapstructure"
+)
+
+// GetQueueTemplates gets the queue templates of a course.
+func (fr *FirebaseRepository) GetQueueTemplates(courseID string) ([]*models.QueueTemplate, error) {
+	docs, err := fr.queueTemplatesRef(courseID).Documents(firebase.Context).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make([]*models.QueueTemplate, 0, len(docs))
+	for _, doc := range docs {
+		t, err := decodeQueueTemplate(doc)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+// GetQueueTemplate gets a queue template of a course.
+func (fr *FirebaseRepository) GetQueueTemplate(courseID string, templateID string) (*models.QueueTemplate, error) {
+	doc, err := fr.queueTemplatesRef(courseID).Doc(templateID).Get(firebase.Context)
+	if status.Code(err) == codes.NotFound {
+		return nil, qerrors.QueueTemplateNotFoundError
+	} else if err != nil {
+		return nil, err
+	}
+
+	return decodeQueueTemplate(doc)
+}
+
+func (fr *FirebaseRepository) CreateQueueTemplate(c *models.CreateQueueTemplateRequest) (*models.QueueTemplate, error) {
+	if err := validQueueTemplate(c.Name, c.OrderingPolicy, c.Categories); err != nil {
+		return nil, err
+	}
+	if _, err := fr.GetCourseByID(c.CourseID); err != nil {
+		return nil, err
+	}
+
+	template := &models.QueueTemplate{
+		CourseID:           c.CourseID,
+		Name:               c.Name,
+		Title:              c.Title,
+		Description:        c.Description,
+		Location:           c.Location,
+		ShowMeetingLinks:   c.ShowMeetingLinks,
+		AllowTicketEditing: c.AllowTicketEditing,
+		FaceMaskPolicy:     c.FaceMaskPolicy,
+		RejoinCooldown:     c.RejoinCooldown,
+		OrderingPolicy:     c.OrderingPolicy,
+		Categories:         c.Categories,
+	}
+
+	ref, _, err := fr.queueTemplatesRef(c.CourseID).Add(firebase.Context, map[string]interface{}{
+		"courseID":           template.CourseID,
+		"name":               template.Name,
+		"title":              template.Title,
+		"description":        template.Description,
+		"location":           template.Location,
+		"showMeetingLinks":   template.ShowMeetingLinks,
+		"allowTicketEditing": template.AllowTicketEditing,
+		"faceMaskPolicy":     template.FaceMaskPolicy,
+		"rejoinCooldown":     template.RejoinCooldown,
+		"orderingPolicy":     template.OrderingPolicy,
+		"categories":         template.Categories,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating queue template: %v", err)
+	}
+
+	template.ID = ref.ID
+	return template, nil
+}
+
+func (fr *FirebaseRepository) EditQueueTemplate(c *models.EditQueueTemplateRequest) error {
+	if err := validQueueTemplate(c.Name, c.OrderingPolicy, c.Categories); err != nil {
+		return err
+	}
+
+	_, err := fr.queueTemplatesRef(c.CourseID).Doc(c.TemplateID).Update(firebase.Context, []firestore.Update{
+		{Path: "name", Value: c.Name},
+		{Path: "title", Value: c.Title},
+		{Path: "description", Value: c.Description},
+		{Path: "location", Value: c.Location},
+		{Path: "showMeetingLinks", Value: c.ShowMeetingLinks},
+		{Path: "allowTicketEditing", Value: c.AllowTicketEditing},
+		{Path: "faceMaskPolicy", Value: c.FaceMaskPolicy},
+		{Path: "rejoinCooldown", Value: c.RejoinCooldown},
+		{Path: "orderingPolicy", Value: c.OrderingPolicy},
+		{Path: "categories", Value: c.Categories},
+	})
+	if status.Code(err) == codes.NotFound {
+		return qerrors.QueueTemplateNotFoundError
+	}
+	return err
+}
+
+func (fr *FirebaseRepository) DeleteQueueTemplate(c *models.DeleteQueueTemplateRequest) error {
+	_, err := fr.queueTemplatesRef(c.CourseID).Doc(c.TemplateID).Delete(firebase.Context)
+	return err
+}
+
+// queueTemplatesRef returns the collection of a course's queue templates.
+func (fr *FirebaseRepository) queueTemplatesRef(courseID string) *firestore.CollectionRef {
+	return fr.firestoreClient.Collection(models.FirestoreCoursesCollection).Doc(courseID).Collection(models.FirestoreQueueTemplatesCollection)
+}
+
+// decodeQueueTemplate destructures a queue template document.
+func decodeQueueTemplate(doc *firestore.DocumentSnapshot) (*models.QueueTemplate, error) {
+	var t models.QueueTemplate
+	err := mapstructure.Decode(doc.Data(), &t)
+	if err != nil {
+		return nil, err
+	}
+
+	t.ID = doc.Ref.ID
+	return &t, nil
+}
+
+// validQueueTemplate returns an error if a queue template has no name, or if the queues created from it would be
+// invalid.
+func validQueueTemplate(name string, policy models.OrderingPolicy, categories []string) error {
+	if strings.TrimSpace(name) == "" {
+		return qerrors.InvalidQueueTemplateError
+	}
+	if err := validOrderingPolicy(policy); err != nil {
+		return err
+	}
+	return validCategories(categories)
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"signmeup/internal/models"
+	"signmeup/internal/qerrors"
+)
+
+// ticketActor is a set of the roles a user has with respect to a ticket.
+type ticketActor int
+
+const (
+	// actorOwner is the student who created the ticket.
+	actorOwner ticketActor = 1 << iota
+	// actorStaff is a staff member of the ticket's course.
+	actorStaff
+)
+
+// ticketTransitions maps each status to the statuses a ticket may move to from it, and who may make each move. A move
+// to the same status only changes the ticket's description. Completed tickets are final.
+var ticketTransitions = map[models.TicketStatus]map[models.TicketStatus]ticketActor{
+	models.StatusWaiting: {
+		models.StatusWaiting:  actorOwner | actorStaff,
+		models.StatusClaimed:  actorStaff,
+		models.StatusMissing:  actorStaff,
+		models.StatusComplete: actorStaff,
+	},
+	models.StatusClaimed: {
+		models.StatusReturned: actorStaff,
+		models.StatusMissing:  actorStaff,
+		models.StatusComplete: actorStaff,
+	},
+	models.StatusMissing: {
+		models.StatusClaimed:  actorStaff,
+		models.StatusReturned: actorOwner | actorStaff,
+		models.StatusComplete: actorStaff,
+	},
+	models.StatusReturned: {
+		models.StatusReturned: actorOwner | actorStaff,
+		models.StatusClaimed:  actorStaff,
+		models.StatusMissing:  actorStaff,
+		models.StatusComplete: actorStaff,
+	},
+	models.StatusComplete: {},
+}
+
+// ticketActorOf returns the roles the user has with respect to a ticket in the given queue.
+func ticketActorOf(user *models.User, queue *models.Queue, ticket *models.Ticket) ticketActor {
+	var actor ticketActor
+	if ticket.User.UserID == user.ID {
+		actor |= actorOwner
+	}
+	if user.HasStaffPermission(queue.CourseID) {
+		actor |= actorStaff
+	}
+	return actor
+}
+
+// checkTransition returns a *qerrors.TicketTransitionError if a ticket may not move between the given statuses, or if
+// actor may not make the move.
+func checkTransition(from models.TicketStatus, to models.TicketStatus, actor ticketActor) error {
+	allowed, ok := ticketTransitions[from][to]
+	if !ok {
+		return &qerrors.TicketTransitionError{From: string(from), To: string(to)}
+	}
+	if allowed&actor == 0 {
+		return &qerrors.TicketTransitionError{From: string(from), To: string(to), Forbidden: true}
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package repository
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"signmeup/internal/models"
+	"signmeup/internal/qerrors"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// bulkUploadRoles maps the roles accepted in bulk upload data to the permissions they grant.
+var bulkUploadRoles = map[string]models.CoursePermission{
+	"ADMIN":   models.CourseAdmin,
+	"HTA":     models.CourseAdmin,
+	"STAFF":   models.CourseStaff,
+	"UTA":     models.CourseStaff,
+	"STUDENT": models.CourseStudent,
+}
+
+// bulkUploadRow is a row of bulk upload data. Rows that cannot be applied have a reason for rejecting them.
+type bulkUploadRow struct {
+	row        int
+	email      string
+	permission string
+	courseCode string
+	courseName string
+	reason     string
+}
+
+// parseBulkUpload reads the rows of bulk upload data. The data as a whole is rejected if it is not valid CSV or its
+// header lacks a column, but invalid rows are only marked as such.
+func parseBulkUpload(data string) ([]*bulkUploadRow, error) {
+	reader := csv.NewReader(strings.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, qerrors.BulkUploadHeaderError
+	}
+
+	// Find the columns by name.
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"email", "role", "course_code", "course_name"} {
+		if _, ok := columns[name]; !ok {
+			return nil, qerrors.BulkUploadHeaderError
+		}
+	}
+	field := func(record []string, name string) string {
+		if i := columns[name]; i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	rows := make([]*bulkUploadRow, 0, len(records)-1)
+	for i, record := range records[1:] {
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		role := strings.ToUpper(field(record, "role"))
+		row := &bulkUploadRow{
+			row:        i + 2,
+			email:      normalizeEmail(field(record, "email")),
+			permission: string(bulkUploadRoles[role]),
+			// Course codes are stored in lowercase, as they always have been by bulk uploads.
+			courseCode: strings.ToLower(field(record, "course_code")),
+			courseName: field(record, "course_name"),
+		}
+		switch {
+		case row.email == "":
+			row.reason = "missing email"
+		case row.courseCode == "":
+			row.reason = "missing course code"
+		case row.permission == "":
+			row.reason = fmt.Sprintf("unknown role %q", role)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// bulkUploader applies the rows of a bulk upload using a repository, keeping track of the courses and invites it has
+// seen so that each is only looked up once.
+type bulkUploader struct {
+	r      Repository
+	c      *models.BulkUploadRequest
+	report *models.BulkUploadReport
+	// courses maps course codes to courses in the upload's term. In a dry run, courses that would be created have no ID.
+	courses map[string]*models.Course
+	// invites maps course codes to the permissions of their unexpired invites, by email.
+	invites map[string]map[string]string
+}
+
+// bulkUpload creates the courses and permissions described by a BulkUploadRequest using the given repository. Rows that
+// cannot be applied are rejected in the report without stopping the upload, and rows describing roles or invites that
+// already exist are left unchanged, so uploading the same data twice has no further effect. The upload is recorded in
+// the audit log, as is each permission it grants.
+func bulkUpload(r Repository, c *models.BulkUploadRequest) (*models.BulkUploadReport, error) {
+	rows, err := parseBulkUpload(c.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	u := &bulkUploader{
+		r: r,
+		c: c,
+		report: &models.BulkUploadReport{
+			DryRun:         c.DryRun,
+			CreatedCourses: []string{},
+			Rows:           make([]*models.BulkUploadRowReport, 0, len(rows)),
+		},
+		courses: make(map[string]*models.Course),
+		invites: make(map[string]map[string]string),
+	}
+
+	// seen maps each course code and email to the first row that gave the email a role in the course.
+	seen := make(map[[2]string]int)
+	counts := make(map[models.BulkUploadOutcome]int)
+	for _, row := range rows {
+		report := &models.BulkUploadRowReport{
+			Row:        row.row,
+			Email:      row.email,
+			CourseCode: row.courseCode,
+			Permission: row.permission,
+		}
+		u.report.Rows = append(u.report.Rows, report)
+
+		key := [2]string{row.courseCode, row.email}
+		if first, ok := seen[key]; ok && row.reason == "" {
+			row.reason = fmt.Sprintf("duplicate of row %d", first)
+		}
+
+		if row.reason == "" {
+			seen[key] = row.row
+			report.Outcome, err = u.apply(row)
+			if err != nil {
+				row.reason = err.Error()
+			}
+		}
+		if row.reason != "" {
+			report.Outcome = models.BulkUploadRejected
+			report.Reason = row.reason
+		}
+		counts[report.Outcome]++
+	}
+
+	if !c.DryRun {
+		entry := newAuditLogEntry(models.AuditBulkUpload, c.CreatedBy, "", c.Term, map[string]models.AuditChange{
+			"courses":     {New: len(u.report.CreatedCourses)},
+			"permissions": {New: counts[models.BulkUploadGranted]},
+			"invites":     {New: counts[models.BulkUploadInvited]},
+			"rejected":    {New: counts[models.BulkUploadRejected]},
+		})
+		if err := r.AddAuditLog(entry); err != nil {
+			glog.Errorf("%v: %+v\n", err, entry)
+		}
+	}
+	return u.report, nil
+}
+
+// apply gives a row's email its role in the row's course, creating the course if needed.
+func (u *bulkUploader) apply(row *bulkUploadRow) (models.BulkUploadOutcome, error) {
+	course, err := u.course(row)
+	if err != nil {
+		return "", err
+	}
+
+	// As with AddPermission, an email that cannot be found is invited.
+	outcome := models.BulkUploadInvited
+	if user, err := u.r.GetUserByEmail(row.email); err == nil {
+		if string(course.CoursePermissions[user.ID]) == row.permission {
+			return models.BulkUploadUnchanged, nil
+		}
+		outcome = models.BulkUploadGranted
+	} else {
+		invites, err := u.courseInvites(course)
+		if err != nil {
+			return "", err
+		}
+		if invites[row.email] == row.permission {
+			return models.BulkUploadUnchanged, nil
+		}
+		invites[row.email] = row.permission
+	}
+
+	if u.c.DryRun {
+		return outcome, nil
+	}
+	err = u.r.AddPermission(&models.AddCoursePermissionRequest{
+		CourseID:   course.ID,
+		Email:      row.email,
+		Permission: row.permission,
+		Actor:      u.c.CreatedBy,
+	})
+	if err != nil {
+		return "", err
+	}
+	return outcome, nil
+}
+
+// course gets the course of a row in the upload's term, creating it if it does not exist.
+func (u *bulkUploader) course(row *bulkUploadRow) (*models.Course, error) {
+	if course, ok := u.courses[row.courseCode]; ok {
+		return course, nil
+	}
+
+	course, err := u.r.GetCourseByInfo(row.courseCode, u.c.Term)
+	if err != nil {
+		if err != qerrors.CourseNotFoundError {
+			return nil, err
+		}
+		if row.courseName == "" {
+			return nil, errors.New("missing course name for new course " + row.courseCode)
+		}
+
+		if u.c.DryRun {
+			course = &models.Course{
+				Title:             row.courseName,
+				Code:              row.courseCode,
+				Term:              u.c.Term,
+				CoursePermissions: map[string]models.CoursePermission{},
+			}
+		} else {
+			course, err = u.r.CreateCourse(&models.CreateCourseRequest{
+				Title:     row.courseName,
+				Code:      row.courseCode,
+				Term:      u.c.Term,
+				CreatedBy: u.c.CreatedBy,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		u.report.CreatedCourses = append(u.report.CreatedCourses, row.courseCode)
+	}
+
+	u.courses[row.courseCode] = course
+	return course, nil
+}
+
+// courseInvites gets the permissions of a course's unexpired invites, by email.
+func (u *bulkUploader) courseInvites(course *models.Course) (map[string]string, error) {
+	if invites, ok := u.invites[course.Code]; ok {
+		return invites, nil
+	}
+
+	invites := make(map[string]string)
+	// Courses that do not exist yet have no invites.
+	if course.ID != "" {
+		courseInvites, err := u.r.GetCourseInvites(course.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, invite := range unexpiredInvites(courseInvites, time.Now()) {
+			invites[invite.Email] = invite.Permission
+		}
+	}
+	u.invites[course.Code] = invites
+	return invites, nil
+}
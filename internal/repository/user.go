@@ -3,13 +3,10 @@ package repository
 import (
 	"cloud.google.com/go/firestore"
 	"fmt"
-	"github.com/golang/glog"
 	"github.com/google/uuid"
 	"github.com/mitchellh/mapstructure"
 	"google.golang.org/api/iterator"
 	"log"
-	"net/http"
-	"signmeup/internal/config"
 	"signmeup/internal/firebase"
 	"signmeup/internal/models"
 	"signmeup/internal/qerrors"
@@ -46,7 +43,7 @@ func (fr *FirebaseRepository) initializeUserProfilesListener() {
 	done := make(chan bool)
 	query := fr.firestoreClient.Collection(models.FirestoreUserProfilesCollection).Query
 	go func() {
-		err := fr.createCollectionInitializer(query, &done, handleDocs)
+		err := fr.createCollectionInitializer(firebase.Context, query, &done, handleDocs)
 		if err != nil {
 			log.Panicf("error creating user profiles collection listener: %v\n", err)
 		}
@@ -54,22 +51,6 @@ func (fr *FirebaseRepository) initializeUserProfilesListener() {
 	<-done
 }
 
-// VerifySessionCookie verifies that the given session cookie is valid and returns the associated User if valid.
-func (fr *FirebaseRepository) VerifySessionCookie(sessionCookie *http.Cookie) (*models.User, error) {
-	decoded, err := fr.authClient.VerifySessionCookieAndCheckRevoked(firebase.Context, sessionCookie.Value)
-
-	if err != nil {
-		return nil, fmt.Errorf("error verifying cookie: %v\n", err)
-	}
-
-	user, err := fr.GetUserByID(decoded.UID)
-	if err != nil {
-		return nil, fmt.Errorf("error getting user from cookie: %v\n", err)
-	}
-
-	return user, nil
-}
-
 func (fr *FirebaseRepository) GetUserByID(id string) (*models.User, error) {
 	if err := validateID(id); err != nil {
 		return nil, err
@@ -83,9 +64,9 @@ func (fr *FirebaseRepository) GetUserByID(id string) (*models.User, error) {
 	// TODO: Refactor email verification and user profile creation into separate function.
 
 	// Check the Firebase user's email against the list of allowed domains.
-	if len(config.Config.AllowedEmailDomains) > 0 {
+	if len(fr.cfg.AllowedEmailDomains) > 0 {
 		domain := strings.Split(fbUser.Email, "@")[1]
-		if !contains(config.Config.AllowedEmailDomains, domain) {
+		if !contains(fr.cfg.AllowedEmailDomains, domain) {
 			// invalid email domain, delete the user from Firebase Auth
 			_ = fr.authClient.DeleteUser(firebase.Context, fbUser.UID)
 			return nil, qerrors.InvalidEmailError
@@ -118,37 +99,8 @@ func (fr *FirebaseRepository) GetUserByID(id string) (*models.User, error) {
 		}
 
 		// Go through each of the invites and execute them.
-		iter := fr.firestoreClient.Collection(models.FirestoreInvitesCollection).Where("email", "==", fbUser.Email).Documents(firebase.Context)
-		for {
-			// Get this document.
-			doc, err := iter.Next()
-			if err == iterator.Done {
-				break
-			}
-			if err != nil {
-				return nil, err
-			}
-			// Decode this document.
-			var invite models.CourseInvite
-			err = mapstructure.Decode(doc.Data(), &invite)
-			if err != nil {
-				return nil, err
-			}
-			// Execute the invite.
-			err = fr.AddPermission(&models.AddCoursePermissionRequest{
-				CourseID:   invite.CourseID,
-				Email:      invite.Email,
-				Permission: invite.Permission,
-			})
-			if err != nil {
-				glog.Warningf("there was a problem adding course permission to a user: %v\n", err)
-			}
-
-			// Delete the doc.
-			_, err = doc.Ref.Delete(firebase.Context)
-			if err != nil {
-				glog.Warningf("there was a problem deleting invite: %v\n", err)
-			}
+		if err := fr.redeemInvites(fbUser.UID, fbUser.Email); err != nil {
+			return nil, err
 		}
 	}
 
@@ -213,8 +165,14 @@ func (fr *FirebaseRepository) MakeAdminByEmail(u *models.MakeAdminByEmailRequest
 			Value: u.IsAdmin,
 		},
 	})
+	if err != nil {
+		return err
+	}
 
-	return err
+	diff := make(map[string]models.AuditChange)
+	addAuditChange(diff, "isAdmin", user.IsAdmin, u.IsAdmin)
+	fr.recordAudit(newAuditLogEntry(models.AuditMakeAdmin, u.Actor, "", u.Email, diff))
+	return nil
 }
 
 func (fr *FirebaseRepository) Count() int {
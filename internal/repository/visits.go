@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"signmeup/internal/firebase"
+	"signmeup/internal/models"
+	"sort"
+)
+
+// GetStudentVisits gets a student's tickets across every queue of a course, newest first. The tickets are found with a
+// collection group query, which needs a single-field index on user.UserID with collection group scope.
+func (fr *FirebaseRepository) GetStudentVisits(courseID string, userID string) ([]*models.StudentVisit, error) {
+	queues, err := fr.GetCourseQueues(courseID)
+	if err != nil {
+		return nil, err
+	}
+	courseQueues := make(map[string]*models.Queue, len(queues))
+	for _, queue := range queues {
+		courseQueues[queue.ID] = queue
+	}
+
+	docs, err := fr.firestoreClient.CollectionGroup(models.FirestoreTicketsCollection).Where("user.UserID", "==", userID).
+		Documents(firebase.Context).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	visits := make([]*models.StudentVisit, 0, len(docs))
+	helpers := make(map[string]*models.User)
+	for _, doc := range docs {
+		// Tickets are stored under their queue, so the parent of their collection is the queue.
+		queue, ok := courseQueues[doc.Ref.Parent.Parent.ID]
+		if !ok {
+			continue
+		}
+
+		t, err := decodeTicket(doc)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := helpers[t.ClaimedBy]; !ok && t.ClaimedBy != "" {
+			// Helpers whose accounts were deleted are left unnamed.
+			helpers[t.ClaimedBy], _ = fr.GetUserByID(t.ClaimedBy)
+		}
+		visits = append(visits, newStudentVisit(queue, t, helpers[t.ClaimedBy]))
+	}
+
+	sortStudentVisits(visits)
+	return visits, nil
+}
+
+// newStudentVisit describes a ticket of queue that helper, who may be nil, claimed.
+func newStudentVisit(queue *models.Queue, t *models.Ticket, helper *models.User) *models.StudentVisit {
+	visit := &models.StudentVisit{
+		TicketID:    t.ID,
+		QueueID:     queue.ID,
+		QueueTitle:  queue.Title,
+		CreatedAt:   t.CreatedAt,
+		ClaimedAt:   t.ClaimedAt,
+		CompletedAt: t.CompletedAt,
+		Status:      t.Status,
+		Description: t.Description,
+		Category:    t.Category,
+		HelpedBy:    t.ClaimedBy,
+	}
+	if helper != nil {
+		visit.HelpedByName = helper.DisplayName
+	}
+	return visit
+}
+
+// sortStudentVisits sorts visits newest first.
+func sortStudentVisits(visits []*models.StudentVisit) {
+	sort.Slice(visits, func(i, j int) bool {
+		return visits[i].CreatedAt.After(visits[j].CreatedAt)
+	})
+}
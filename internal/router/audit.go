@@ -0,0 +1,74 @@
+package router
+
+import (
+	"net/http"
+	"signmeup/internal/auth"
+	"signmeup/internal/models"
+	"signmeup/internal/qerrors"
+	"signmeup/internal/repository"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+type auditHandler struct {
+	repo repository.Repository
+}
+
+func AuditRoutes(repo repository.Repository, authn *auth.Authenticator) *chi.Mux {
+	h := &auditHandler{repo: repo}
+	router := chi.NewRouter()
+	router.Use(authn.AuthCtx())
+
+	// Only site admins can read the whole audit log. Course admins read their course's log through the course routes.
+	router.With(auth.RequireAdmin()).Get("/", h.getAuditLogsHandler)
+
+	return router
+}
+
+// GET: /?courseID=&actorID=&action=&cursor=&limit=
+func (h *auditHandler) getAuditLogsHandler(w http.ResponseWriter, r *http.Request) {
+	req, err := parseAuditLogsRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	renderAuditLogs(w, r, h.repo, req)
+}
+
+// parseAuditLogsRequest reads the filters and page of an audit log request from its query string.
+func parseAuditLogsRequest(r *http.Request) (*models.GetAuditLogsRequest, error) {
+	query := r.URL.Query()
+	req := &models.GetAuditLogsRequest{
+		CourseID: query.Get("courseID"),
+		ActorID:  query.Get("actorID"),
+		Action:   models.AuditAction(query.Get("action")),
+		Cursor:   query.Get("cursor"),
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return nil, qerrors.InvalidBody
+		}
+		req.Limit = n
+	}
+	return req, nil
+}
+
+// renderAuditLogs responds with the page of the audit log described by req.
+func renderAuditLogs(w http.ResponseWriter, r *http.Request, repo repository.Repository, req *models.GetAuditLogsRequest) {
+	page, err := repo.GetAuditLogs(req)
+	if err != nil {
+		if err == qerrors.AuditCursorNotFoundError {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	render.JSON(w, r, page)
+}
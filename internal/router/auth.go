@@ -7,47 +7,54 @@ import (
 	"net/http"
 	"signmeup/internal/auth"
 	"signmeup/internal/config"
-	"signmeup/internal/firebase"
 	"signmeup/internal/models"
-	repo "signmeup/internal/repository"
+	"signmeup/internal/repository"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 )
 
-func AuthRoutes() *chi.Mux {
+type authHandler struct {
+	cfg      *config.ServerConfig
+	repo     repository.Repository
+	verifier auth.Verifier
+}
+
+func AuthRoutes(cfg *config.ServerConfig, repo repository.Repository, verifier auth.Verifier, authn *auth.Authenticator) *chi.Mux {
+	h := &authHandler{cfg: cfg, repo: repo, verifier: verifier}
 	router := chi.NewRouter()
 
 	// Auth routes that require authentication
 	router.Route("/", func(r chi.Router) {
-		r.Use(auth.AuthCtx())
+		r.Use(authn.AuthCtx())
 
 		// Information about the current user
-		r.Get("/me", getMeHandler)
-		r.Get("/{userID}", getUserHandler)
+		r.Get("/me", h.getMeHandler)
+		r.Get("/invites", h.getMyInvitesHandler)
+		r.Get("/{userID}", h.getUserHandler)
 
 		// Update the current user's information
-		r.Post("/update", updateUserHandler)
-		r.With(auth.RequireAdmin()).Post("/updateByEmail", updateUserByEmailHandler)
+		r.Post("/update", h.updateUserHandler)
+		r.With(auth.RequireAdmin()).Post("/updateByEmail", h.updateUserByEmailHandler)
 
 		// Notification clearing
-		r.Post("/clearNotification", clearNotificationHandler)
-		r.Post("/clearAllNotifications", clearAllNotificationsHandler)
+		r.Post("/clearNotification", h.clearNotificationHandler)
+		r.Post("/clearAllNotifications", h.clearAllNotificationsHandler)
 
 		// Favorite courses
-		r.Post("/addFavoriteCourses", addFavoriteCourseHandler)
-		r.Post("/removeFavoriteCourses", removeFavoriteCourseHandler)
+		r.Post("/addFavoriteCourses", h.addFavoriteCourseHandler)
+		r.Post("/removeFavoriteCourses", h.removeFavoriteCourseHandler)
 	})
 
 	// Alter the current session. No auth middlewares required.
-	router.Post("/session", createSessionHandler)
-	router.Post("/signout", signOutHandler)
+	router.Post("/session", h.createSessionHandler)
+	router.Post("/signout", h.signOutHandler)
 
 	return router
 }
 
 // GET: /me
-func getMeHandler(w http.ResponseWriter, r *http.Request) {
+func (h *authHandler) getMeHandler(w http.ResponseWriter, r *http.Request) {
 	user, err := auth.GetUserFromRequest(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
@@ -60,9 +67,29 @@ func getMeHandler(w http.ResponseWriter, r *http.Request) {
 	}{user.Profile, user.ID})
 }
 
-func getUserHandler(w http.ResponseWriter, r *http.Request) {
+// GET: /invites
+//
+// Lists the current user's pending invites, which are the unexpired invites to their email that have not been redeemed,
+// such as those made to it in a different case after their account was created.
+func (h *authHandler) getMyInvitesHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetUserFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	invites, err := h.repo.GetInvitesByEmail(user.Email)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, invites)
+}
+
+func (h *authHandler) getUserHandler(w http.ResponseWriter, r *http.Request) {
 	userID := chi.URLParam(r, "userID")
-	user, err := repo.Repository.GetUserByID(userID)
+	user, err := h.repo.GetUserByID(userID)
 	if err != nil {
 		// TODO(nthnluu): Refactor into helper function
 		w.WriteHeader(http.StatusNotFound)
@@ -84,7 +111,7 @@ func getUserHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // POST: /update
-func updateUserHandler(w http.ResponseWriter, r *http.Request) {
+func (h *authHandler) updateUserHandler(w http.ResponseWriter, r *http.Request) {
 	var req *models.UpdateUserRequest
 
 	err := json.NewDecoder(r.Body).Decode(&req)
@@ -99,7 +126,7 @@ func updateUserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	req.UserID = user.ID
 
-	err = repo.Repository.UpdateUser(req)
+	err = h.repo.UpdateUser(req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -114,16 +141,23 @@ func updateUserHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // POST: /updateByEmail
-func updateUserByEmailHandler(w http.ResponseWriter, r *http.Request) {
+func (h *authHandler) updateUserByEmailHandler(w http.ResponseWriter, r *http.Request) {
 	var req *models.MakeAdminByEmailRequest
 
-	err := json.NewDecoder(r.Body).Decode(&req)
+	user, err := auth.GetUserFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	req.Actor = user
 
-	err = repo.Repository.MakeAdminByEmail(req)
+	err = h.repo.MakeAdminByEmail(req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -137,49 +171,44 @@ func updateUserByEmailHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // POST: /session
-func createSessionHandler(w http.ResponseWriter, r *http.Request) {
-	authClient, err := firebase.App.Auth(firebase.Context)
-	if err != nil {
-		log.Fatalf("error getting Auth client: %v\n", err)
-	}
-
+func (h *authHandler) createSessionHandler(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Token string `json:"token"`
 	}
 
-	err = json.NewDecoder(r.Body).Decode(&req)
+	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	// Set session expiration to 5 days.
-	expiresIn := config.Config.SessionCookieExpiration
+	expiresIn := h.cfg.SessionCookieExpiration
 
 	// Create the session cookie. This will also verify the ID token in the process.
 	// The session cookie will have the same claims as the ID token.
 	// To only allow session cookie setting on recent sign-in, auth_time in ID token
 	// can be checked to ensure user was recently signed in before creating a session cookie.
-	cookie, err := authClient.SessionCookie(firebase.Context, req.Token, expiresIn)
+	cookie, err := h.verifier.CreateSessionCookie(req.Token, expiresIn)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	var sameSite http.SameSite
-	if config.Config.IsHTTPS {
+	if h.cfg.IsHTTPS {
 		sameSite = http.SameSiteNoneMode
 	} else {
 		sameSite = http.SameSiteLaxMode
 	}
 
 	http.SetCookie(w, &http.Cookie{
-		Name:     config.Config.SessionCookieName,
+		Name:     h.cfg.SessionCookieName,
 		Value:    cookie,
 		MaxAge:   int(expiresIn.Seconds()),
 		HttpOnly: true,
 		SameSite: sameSite,
-		Secure:   config.Config.IsHTTPS,
+		Secure:   h.cfg.IsHTTPS,
 		Path:     "/",
 	})
 
@@ -189,21 +218,21 @@ func createSessionHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // POST: /signout
-func signOutHandler(w http.ResponseWriter, r *http.Request) {
+func (h *authHandler) signOutHandler(w http.ResponseWriter, r *http.Request) {
 	var sameSite http.SameSite
-	if config.Config.IsHTTPS {
+	if h.cfg.IsHTTPS {
 		sameSite = http.SameSiteNoneMode
 	} else {
 		sameSite = http.SameSiteLaxMode
 	}
 
 	http.SetCookie(w, &http.Cookie{
-		Name:     config.Config.SessionCookieName,
+		Name:     h.cfg.SessionCookieName,
 		Value:    "",
 		MaxAge:   -1,
 		HttpOnly: true,
 		SameSite: sameSite,
-		Secure:   config.Config.IsHTTPS,
+		Secure:   h.cfg.IsHTTPS,
 		Path:     "/",
 	})
 
@@ -213,7 +242,7 @@ func signOutHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // POST: notification clear
-func clearNotificationHandler(w http.ResponseWriter, r *http.Request) {
+func (h *authHandler) clearNotificationHandler(w http.ResponseWriter, r *http.Request) {
 	var req *models.ClearNotificationRequest
 
 	user, err := auth.GetUserFromRequest(r)
@@ -229,7 +258,7 @@ func clearNotificationHandler(w http.ResponseWriter, r *http.Request) {
 
 	req.UserID = user.ID
 
-	err = repo.Repository.ClearNotification(req)
+	err = h.repo.ClearNotification(req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -240,7 +269,7 @@ func clearNotificationHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // POST: notification clear all
-func clearAllNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+func (h *authHandler) clearAllNotificationsHandler(w http.ResponseWriter, r *http.Request) {
 	user, err := auth.GetUserFromRequest(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
@@ -248,7 +277,7 @@ func clearAllNotificationsHandler(w http.ResponseWriter, r *http.Request) {
 
 	req := models.ClearAllNotificationsRequest{UserID: user.ID}
 
-	err = repo.Repository.ClearAllNotifications(&req)
+	err = h.repo.ClearAllNotifications(&req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -259,7 +288,7 @@ func clearAllNotificationsHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // POST: add a favorite course
-func addFavoriteCourseHandler(w http.ResponseWriter, r *http.Request) {
+func (h *authHandler) addFavoriteCourseHandler(w http.ResponseWriter, r *http.Request) {
 	user, err := auth.GetUserFromRequest(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
@@ -273,7 +302,7 @@ func addFavoriteCourseHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = repo.Repository.AddFavoriteCourse(user.ID, req.CourseID)
+	err = h.repo.AddFavoriteCourse(user.ID, req.CourseID)
 	if err != nil {
 		glog.Warningln(err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -285,7 +314,7 @@ func addFavoriteCourseHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // POST: remove a favorite course
-func removeFavoriteCourseHandler(w http.ResponseWriter, r *http.Request) {
+func (h *authHandler) removeFavoriteCourseHandler(w http.ResponseWriter, r *http.Request) {
 	user, err := auth.GetUserFromRequest(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
@@ -299,7 +328,7 @@ func removeFavoriteCourseHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = repo.Repository.RemoveFavoriteCourse(user.ID, req.CourseID)
+	err = h.repo.RemoveFavoriteCourse(user.ID, req.CourseID)
 	if err != nil {
 		glog.Warningln(err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
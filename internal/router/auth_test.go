@@ -0,0 +1,95 @@
+package router_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"signmeup/internal/config"
+	"signmeup/internal/models"
+	"signmeup/internal/repository"
+	"signmeup/internal/server"
+)
+
+// fakeVerifier treats the value of a session cookie as the ID of the user it belongs to.
+type fakeVerifier struct{}
+
+func (fakeVerifier) VerifySessionCookie(sessionCookie *http.Cookie) (string, error) {
+	return sessionCookie.Value, nil
+}
+
+func (fakeVerifier) CreateSessionCookie(idToken string, expiresIn time.Duration) (string, error) {
+	return idToken, nil
+}
+
+func TestGetMyInvites(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	// The first user is a site admin, so it is created before the user under test.
+	admin, err := repo.Create(&models.CreateUserRequest{Email: "admin@brown.edu", Password: "password", DisplayName: "Admin"})
+	if err != nil {
+		t.Fatalf("creating admin: %v", err)
+	}
+	student, err := repo.Create(&models.CreateUserRequest{Email: "Student@Brown.edu", Password: "password", DisplayName: "Student"})
+	if err != nil {
+		t.Fatalf("creating student: %v", err)
+	}
+
+	invites := []struct {
+		code      string
+		email     string
+		expiresAt time.Time
+	}{
+		// The student's email in another case does not match their account, so the invite is left pending.
+		{code: "cs0150", email: " student@brown.edu"},
+		{code: "cs0170", email: "student@brown.edu", expiresAt: time.Now().Add(-time.Hour)},
+		{code: "cs0200", email: "other@brown.edu"},
+	}
+	var want string
+	for _, invite := range invites {
+		course, err := repo.CreateCourse(&models.CreateCourseRequest{Title: invite.code, Code: invite.code, Term: "fall"})
+		if err != nil {
+			t.Fatalf("creating course %s: %v", invite.code, err)
+		}
+		err = repo.AddPermission(&models.AddCoursePermissionRequest{
+			CourseID:   course.ID,
+			Email:      invite.email,
+			Permission: string(models.CourseStaff),
+			ExpiresAt:  invite.expiresAt,
+			Actor:      admin,
+		})
+		if err != nil {
+			t.Fatalf("inviting %s to %s: %v", invite.email, invite.code, err)
+		}
+		if invite.code == "cs0150" {
+			want = course.ID
+		}
+	}
+
+	cfg := config.DefaultDevelopmentConfig()
+	ts := httptest.NewServer(server.New(cfg, repo, fakeVerifier{}))
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/v1/users/invites", nil)
+	if err != nil {
+		t.Fatalf("creating request: %v", err)
+	}
+	req.AddCookie(&http.Cookie{Name: cfg.SessionCookieName, Value: student.ID})
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("sending request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	var got []*models.CourseInvite
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("decoding invites: %v", err)
+	}
+	if len(got) != 1 || got[0].CourseID != want || got[0].Permission != string(models.CourseStaff) {
+		t.Errorf("got invites %+v, want only the staff invite to course %s", got, want)
+	}
+}
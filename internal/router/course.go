@@ -1,52 +1,183 @@
 package router
 
 import (
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/golang/glog"
 	"net/http"
 	"signmeup/internal/auth"
+	"signmeup/internal/config"
 	"signmeup/internal/middleware"
 	"signmeup/internal/models"
 	"signmeup/internal/qerrors"
-	repo "signmeup/internal/repository"
+	"signmeup/internal/repository"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 )
 
-func CourseRoutes() *chi.Mux {
+type courseHandler struct {
+	cfg  *config.ServerConfig
+	repo repository.Repository
+}
+
+func CourseRoutes(cfg *config.ServerConfig, repo repository.Repository, authn *auth.Authenticator) *chi.Mux {
+	h := &courseHandler{cfg: cfg, repo: repo}
 	router := chi.NewRouter()
 	// All course routes require authentication.
-	router.Use(auth.AuthCtx())
+	router.Use(authn.AuthCtx())
+
+	// Listing courses
+	router.Get("/", h.listCoursesHandler)
 
 	// Modifying courses themselves
-	router.With(auth.RequireAdmin()).Post("/create", createCourseHandler)
+	router.With(auth.RequireAdmin()).Post("/create", h.createCourseHandler)
 
 	// Get metadata about a course
 	router.Route("/{courseID}", func(router chi.Router) {
 		router.Use(middleware.CourseCtx())
 
 		// Anybody authed can read a course
-		router.Get("/", getCourseHandler)
+		router.Get("/", h.getCourseHandler)
 
 		// Only Admins can delete a course
-		router.With(auth.RequireAdmin()).Delete("/", deleteCourseHandler)
+		router.With(auth.RequireAdmin()).Delete("/", h.deleteCourseHandler)
 
 		// Course modification
-		router.With(auth.RequireCourseAdmin()).Post("/edit", editCourseHandler)
-		router.With(auth.RequireCourseAdmin()).Post("/addPermission", addCoursePermissionHandler)
-		router.With(auth.RequireCourseAdmin()).Post("/removePermission", removeCoursePermissionHandler)
+		router.With(auth.RequireCourseAdmin()).Post("/roster", h.importRosterHandler)
+		router.With(auth.RequireCourseAdmin()).Post("/edit", h.editCourseHandler)
+		router.With(auth.RequireCourseAdmin()).Post("/archive", h.archiveCourseHandler)
+		router.With(auth.RequireCourseAdmin()).Post("/unarchive", h.unarchiveCourseHandler)
+		router.With(auth.RequireCourseAdmin()).Post("/addPermission", h.addCoursePermissionHandler)
+		router.With(auth.RequireCourseAdmin()).Post("/removePermission", h.removeCoursePermissionHandler)
+
+		// Pending invites of emails without an account
+		router.With(auth.RequireCourseAdmin()).Get("/invites", h.getCourseInvitesHandler)
+		router.With(auth.RequireCourseAdmin()).Post("/invites/{inviteID}/edit", h.editInviteHandler)
+		router.With(auth.RequireCourseAdmin()).Delete("/invites/{inviteID}", h.revokeInviteHandler)
+
+		// End-of-week reports
+		router.With(auth.RequireCourseAdmin()).Get("/analytics", h.getCourseAnalyticsHandler)
+
+		// A student's past visits, for staff helping them
+		router.With(auth.RequireStaffForCourse()).Get("/students/{userID}/visits", h.getStudentVisitsHandler)
+
+		// Data export
+		router.With(auth.RequireStaffForCourse()).Get("/export", h.exportCourseHandler)
+
+		// The course's audit log
+		router.With(auth.RequireCourseAdmin()).Get("/audit", h.getCourseAuditLogsHandler)
+
+		// Queue templates. Staff can read them to create queues from them.
+		router.With(auth.RequireStaffForCourse()).Get("/templates", h.getQueueTemplatesHandler)
+		router.With(auth.RequireCourseAdmin()).Post("/templates", h.createQueueTemplateHandler)
+		router.With(auth.RequireCourseAdmin()).Post("/templates/{templateID}/edit", h.editQueueTemplateHandler)
+		router.With(auth.RequireCourseAdmin()).Delete("/templates/{templateID}", h.deleteQueueTemplateHandler)
+
+		// Recurring office hours schedules
+		router.With(auth.RequireStaffForCourse()).Get("/schedules", h.getSchedulesHandler)
+		router.With(auth.RequireCourseAdmin()).Post("/schedules", h.createScheduleHandler)
+		router.With(auth.RequireCourseAdmin()).Post("/schedules/{scheduleID}/edit", h.editScheduleHandler)
+		router.With(auth.RequireCourseAdmin()).Delete("/schedules/{scheduleID}", h.deleteScheduleHandler)
 	})
-	router.With(auth.RequireAdmin()).Post("/bulkUpload", bulkUploadHandler)
+	router.With(auth.RequireAdmin()).Post("/bulkUpload", h.bulkUploadHandler)
+	router.With(auth.RequireAdmin()).Post("/rollover", h.rolloverTermHandler)
+	router.With(auth.RequireAdmin()).Post("/archiveByTerm", h.archiveCoursesByTermHandler)
 
 	return router
 }
 
+// GET: /?term=&codePrefix=&archived=&staffed=&sort=&order=&cursor=&limit=
+func (h *courseHandler) listCoursesHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetUserFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	req, err := parseListCoursesRequest(r, user)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.repo.ListCourses(req)
+	if err != nil {
+		if err == qerrors.InvalidCourseCursorError || err == qerrors.InvalidCourseSortError {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	render.JSON(w, r, page)
+}
+
+// parseListCoursesRequest reads the filters, order and page of a course listing for user from its query string.
+// Archived courses are hidden unless archived is true, or all to list both.
+func parseListCoursesRequest(r *http.Request, user *models.User) (*models.ListCoursesRequest, error) {
+	query := r.URL.Query()
+	req := &models.ListCoursesRequest{
+		Term:       query.Get("term"),
+		CodePrefix: query.Get("codePrefix"),
+		Viewer:     user,
+		Sort:       models.CourseSort(query.Get("sort")),
+		Cursor:     query.Get("cursor"),
+	}
+
+	switch archived := query.Get("archived"); archived {
+	case "", "false", "true":
+		onlyArchived := archived == "true"
+		req.Archived = &onlyArchived
+	case "all":
+	default:
+		return nil, qerrors.InvalidBody
+	}
+
+	if staffed := query.Get("staffed"); staffed != "" {
+		b, err := strconv.ParseBool(staffed)
+		if err != nil {
+			return nil, qerrors.InvalidBody
+		}
+		if b {
+			req.CourseIDs = user.StaffedCourses()
+		}
+	}
+
+	switch order := query.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		req.Descending = true
+	default:
+		return nil, qerrors.InvalidBody
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return nil, qerrors.InvalidBody
+		}
+		req.Limit = n
+	}
+	return req, nil
+}
+
 // GET: /{courseID}
-func getCourseHandler(w http.ResponseWriter, r *http.Request) {
+func (h *courseHandler) getCourseHandler(w http.ResponseWriter, r *http.Request) {
 	courseID := r.Context().Value("courseID").(string)
 
-	course, err := repo.Repository.GetCourseByID(courseID)
+	user, err := auth.GetUserFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	course, err := h.repo.GetCourseByID(courseID)
 	if err != nil {
 		if err == qerrors.CourseNotFoundError {
 			http.Error(w, err.Error(), http.StatusNotFound)
@@ -56,11 +187,17 @@ func getCourseHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Restricted courses are only visible to the users enrolled in them.
+	if course.RestrictToEnrolled && !course.IsEnrolled(user) {
+		http.Error(w, qerrors.NotEnrolledError.Error(), http.StatusForbidden)
+		return
+	}
+
 	render.JSON(w, r, course)
 }
 
 // POST: /create
-func createCourseHandler(w http.ResponseWriter, r *http.Request) {
+func (h *courseHandler) createCourseHandler(w http.ResponseWriter, r *http.Request) {
 	var req *models.CreateCourseRequest
 
 	user, err := auth.GetUserFromRequest(r)
@@ -75,7 +212,7 @@ func createCourseHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	req.CreatedBy = user
 
-	c, err := repo.Repository.CreateCourse(req)
+	c, err := h.repo.CreateCourse(req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -85,10 +222,16 @@ func createCourseHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // DELETE: /{courseID}
-func deleteCourseHandler(w http.ResponseWriter, r *http.Request) {
+func (h *courseHandler) deleteCourseHandler(w http.ResponseWriter, r *http.Request) {
 	courseID := r.Context().Value("courseID").(string)
 
-	err := repo.Repository.DeleteCourse(&models.DeleteCourseRequest{CourseID: courseID})
+	user, err := auth.GetUserFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	err = h.repo.DeleteCourse(&models.DeleteCourseRequest{CourseID: courseID, Actor: user})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -99,17 +242,24 @@ func deleteCourseHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // POST: /{courseID}/edit
-func editCourseHandler(w http.ResponseWriter, r *http.Request) {
+func (h *courseHandler) editCourseHandler(w http.ResponseWriter, r *http.Request) {
 	var req *models.EditCourseRequest
 
-	err := json.NewDecoder(r.Body).Decode(&req)
+	user, err := auth.GetUserFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 	req.CourseID = r.Context().Value("courseID").(string)
+	req.Actor = user
 
-	err = repo.Repository.EditCourse(req)
+	err = h.repo.EditCourse(req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -119,20 +269,72 @@ func editCourseHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("Successfully edited course " + req.CourseID))
 }
 
+// POST: /{courseID}/archive
+func (h *courseHandler) archiveCourseHandler(w http.ResponseWriter, r *http.Request) {
+	h.setCourseArchived(w, r, true)
+}
+
+// POST: /{courseID}/unarchive
+func (h *courseHandler) unarchiveCourseHandler(w http.ResponseWriter, r *http.Request) {
+	h.setCourseArchived(w, r, false)
+}
+
+// setCourseArchived archives or unarchives the course of the request.
+func (h *courseHandler) setCourseArchived(w http.ResponseWriter, r *http.Request, archived bool) {
+	user, err := auth.GetUserFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	req := &models.SetCourseArchivedRequest{
+		CourseID: r.Context().Value("courseID").(string),
+		Archived: archived,
+		Actor:    user,
+	}
+	err = h.repo.SetCourseArchived(req)
+	if err != nil {
+		if err == qerrors.CourseNotFoundError {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(200)
+	if archived {
+		w.Write([]byte("Successfully archived course " + req.CourseID))
+	} else {
+		w.Write([]byte("Successfully unarchived course " + req.CourseID))
+	}
+}
+
 // POST: /{courseID}/addPermission
-func addCoursePermissionHandler(w http.ResponseWriter, r *http.Request) {
+func (h *courseHandler) addCoursePermissionHandler(w http.ResponseWriter, r *http.Request) {
 	var req *models.AddCoursePermissionRequest
 
-	err := json.NewDecoder(r.Body).Decode(&req)
+	user, err := auth.GetUserFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 	req.CourseID = chi.URLParam(r, "courseID")
+	req.Actor = user
 
-	err = repo.Repository.AddPermission(req)
+	err = h.repo.AddPermission(req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		if err == qerrors.InvalidPermissionError {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -140,18 +342,56 @@ func addCoursePermissionHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("Successfully added course permission to " + req.CourseID))
 }
 
+// POST: /{courseID}/roster
+func (h *courseHandler) importRosterHandler(w http.ResponseWriter, r *http.Request) {
+	var req *models.ImportRosterRequest
+
+	user, err := auth.GetUserFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.CourseID = r.Context().Value("courseID").(string)
+	req.Actor = user
+
+	result, err := h.repo.ImportRoster(req)
+	if err != nil {
+		if err == qerrors.CourseNotFoundError {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	render.JSON(w, r, result)
+}
+
 // POST: /{courseID}/removePermission
-func removeCoursePermissionHandler(w http.ResponseWriter, r *http.Request) {
+func (h *courseHandler) removeCoursePermissionHandler(w http.ResponseWriter, r *http.Request) {
 	var req *models.RemoveCoursePermissionRequest
 
-	err := json.NewDecoder(r.Body).Decode(&req)
+	user, err := auth.GetUserFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 	req.CourseID = r.Context().Value("courseID").(string)
+	req.Actor = user
 
-	err = repo.Repository.RemovePermission(req)
+	err = h.repo.RemovePermission(req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -162,22 +402,223 @@ func removeCoursePermissionHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // POST: /bulkUpload
-func bulkUploadHandler(w http.ResponseWriter, r *http.Request) {
+func (h *courseHandler) bulkUploadHandler(w http.ResponseWriter, r *http.Request) {
 	var req *models.BulkUploadRequest
 
-	err := json.NewDecoder(r.Body).Decode(&req)
+	user, err := auth.GetUserFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.CreatedBy = user
+
+	report, err := h.repo.BulkUpload(req)
+	if err != nil {
+		var parseErr *csv.ParseError
+		if err == qerrors.BulkUploadHeaderError || errors.As(err, &parseErr) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	render.JSON(w, r, report)
+}
+
+// POST: /rollover
+func (h *courseHandler) rolloverTermHandler(w http.ResponseWriter, r *http.Request) {
+	var req *models.RolloverTermRequest
+
+	user, err := auth.GetUserFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	req.Actor = user
 
-	err = repo.Repository.BulkUpload(req)
-	fmt.Println(err)
+	report, err := h.repo.RolloverTerm(req)
+	if err != nil {
+		if err == qerrors.InvalidRolloverError {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	render.JSON(w, r, report)
+}
+
+// POST: /archiveByTerm
+func (h *courseHandler) archiveCoursesByTermHandler(w http.ResponseWriter, r *http.Request) {
+	var req *models.ArchiveCoursesByTermRequest
+
+	user, err := auth.GetUserFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Term == "" {
+		http.Error(w, qerrors.InvalidBody.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Actor = user
+
+	archived, err := h.repo.ArchiveCoursesByTerm(req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.WriteHeader(200)
-	w.Write([]byte("Successfully bulk-uploaded"))
+	w.Write([]byte(fmt.Sprintf("Successfully archived %d courses in %s", archived, req.Term)))
+}
+
+// GET: /{courseID}/audit?actorID=&action=&cursor=&limit=
+func (h *courseHandler) getCourseAuditLogsHandler(w http.ResponseWriter, r *http.Request) {
+	req, err := parseAuditLogsRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Course admins may only read their own course's entries.
+	req.CourseID = r.Context().Value("courseID").(string)
+
+	renderAuditLogs(w, r, h.repo, req)
+}
+
+// GET: /{courseID}/analytics?from=&to=&tz=
+func (h *courseHandler) getCourseAnalyticsHandler(w http.ResponseWriter, r *http.Request) {
+	from, to, location, err := parseReportPeriod(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	analytics, err := h.repo.GetCourseAnalytics(&models.GetCourseAnalyticsRequest{
+		CourseID: r.Context().Value("courseID").(string),
+		From:     from,
+		To:       to,
+		Location: location,
+	})
+	if err != nil {
+		if err == qerrors.CourseNotFoundError {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	render.JSON(w, r, analytics)
+}
+
+// GET: /{courseID}/students/{userID}/visits
+func (h *courseHandler) getStudentVisitsHandler(w http.ResponseWriter, r *http.Request) {
+	courseID := r.Context().Value("courseID").(string)
+
+	visits, err := h.repo.GetStudentVisits(courseID, chi.URLParam(r, "userID"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, visits)
+}
+
+// GET: /{courseID}/export?from=&to=&tz=&format=
+func (h *courseHandler) exportCourseHandler(w http.ResponseWriter, r *http.Request) {
+	courseID := r.Context().Value("courseID").(string)
+
+	from, to, _, err := parseReportPeriod(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	queues, err := h.repo.GetCourseQueues(courseID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writer, err := newTicketExportWriter(w, models.ExportFormat(r.URL.Query().Get("format")), "course-"+courseID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Stream the export a queue at a time, so that large courses need not be held in memory. Once rows have been sent
+	// the status can no longer be changed, so later errors end the export early.
+	for _, queue := range queues {
+		tickets, err := h.repo.GetTickets(queue.ID, from, to)
+		if err == nil {
+			err = writeTicketExport(writer, queue, tickets, h.cfg.ExportHashKey)
+		}
+		if err != nil {
+			glog.Warningf("error exporting course %v: %v\n", courseID, err)
+			return
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		glog.Warningf("error exporting course %v: %v\n", courseID, err)
+	}
+}
+
+// parseReportPeriod reads the period covered by a report from the from, to and tz query parameters. Reports cover the
+// week up to now unless told otherwise, and dates are taken as midnight in tz, or UTC if it is not given.
+func parseReportPeriod(r *http.Request) (from time.Time, to time.Time, location *time.Location, err error) {
+	query := r.URL.Query()
+
+	location = time.UTC
+	if tz := query.Get("tz"); tz != "" {
+		if location, err = time.LoadLocation(tz); err != nil {
+			return
+		}
+	}
+
+	to = time.Now()
+	if value := query.Get("to"); value != "" {
+		if to, err = parseReportTime(value, location); err != nil {
+			return
+		}
+	}
+	from = to.AddDate(0, 0, -7)
+	if value := query.Get("from"); value != "" {
+		if from, err = parseReportTime(value, location); err != nil {
+			return
+		}
+	}
+
+	if !from.Before(to) {
+		err = errors.New("from must be before to")
+	}
+	return
+}
+
+// parseReportTime parses an RFC 3339 timestamp, or a date which is taken as midnight in location.
+func parseReportTime(value string, location *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, location)
 }
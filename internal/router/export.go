@@ -0,0 +1,156 @@
+package router
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"signmeup/internal/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportHeader is the header row of CSV exports, in the order of exportRecord's fields.
+var exportHeader = []string{
+	"courseID", "queueID", "queueTitle", "queueLocation", "ticketID", "status", "category", "description",
+	"createdAt", "claimedAt", "claimedBy", "completedAt", "anonymized", "userID", "email", "displayName", "pronouns",
+}
+
+// ticketExportWriter writes the rows of an export in one of the supported formats.
+type ticketExportWriter interface {
+	WriteRow(row *models.TicketExportRow) error
+	// Flush sends the rows written so far to the client.
+	Flush() error
+}
+
+// newTicketExportWriter sets the headers of an export response and returns a writer for its rows. name is the name of
+// the downloaded file, without an extension.
+func newTicketExportWriter(w http.ResponseWriter, format models.ExportFormat, name string) (ticketExportWriter, error) {
+	switch format {
+	case "", models.ExportCSV:
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".csv"))
+		writer := &csvExportWriter{w: w, csv: csv.NewWriter(w)}
+		return writer, writer.csv.Write(exportHeader)
+	case models.ExportNDJSON:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".ndjson"))
+		return &ndjsonExportWriter{w: w, encoder: json.NewEncoder(w)}, nil
+	default:
+		return nil, errors.New("unknown export format " + string(format))
+	}
+}
+
+type csvExportWriter struct {
+	w   http.ResponseWriter
+	csv *csv.Writer
+}
+
+func (e *csvExportWriter) WriteRow(row *models.TicketExportRow) error {
+	return e.csv.Write(exportRecord(row))
+}
+
+func (e *csvExportWriter) Flush() error {
+	e.csv.Flush()
+	if err := e.csv.Error(); err != nil {
+		return err
+	}
+	flush(e.w)
+	return nil
+}
+
+type ndjsonExportWriter struct {
+	w       http.ResponseWriter
+	encoder *json.Encoder
+}
+
+func (e *ndjsonExportWriter) WriteRow(row *models.TicketExportRow) error {
+	// Encode writes each row on its own line.
+	return e.encoder.Encode(row)
+}
+
+func (e *ndjsonExportWriter) Flush() error {
+	flush(e.w)
+	return nil
+}
+
+// flush sends buffered response data to the client, if the response supports it.
+func flush(w http.ResponseWriter) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// exportRecord lists the fields of a row in the order of exportHeader. The fields that users write themselves are
+// escaped, so that spreadsheets opening the export do not run them as formulas.
+func exportRecord(row *models.TicketExportRow) []string {
+	return []string{
+		row.CourseID, row.QueueID, csvText(row.QueueTitle), csvText(row.QueueLocation), row.TicketID, string(row.Status),
+		csvText(row.Category), csvText(row.Description), row.CreatedAt, row.ClaimedAt, row.ClaimedBy, row.CompletedAt,
+		strconv.FormatBool(row.Anonymized), row.UserID, csvText(row.Email), csvText(row.DisplayName),
+		csvText(row.Pronouns),
+	}
+}
+
+// csvText prefixes text that a spreadsheet would read as a formula with a quote, which makes it read as text instead.
+func csvText(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+// newTicketExportRow describes a ticket of queue. The identity of the student on an anonymized ticket is replaced by
+// an HMAC of their ID under hashKey, so that their tickets can still be grouped together.
+func newTicketExportRow(queue *models.Queue, ticket *models.Ticket, hashKey string) *models.TicketExportRow {
+	row := &models.TicketExportRow{
+		CourseID:      queue.CourseID,
+		QueueID:       queue.ID,
+		QueueTitle:    queue.Title,
+		QueueLocation: queue.Location,
+		TicketID:      ticket.ID,
+		Status:        ticket.Status,
+		Category:      ticket.Category,
+		Description:   ticket.Description,
+		CreatedAt:     exportTime(ticket.CreatedAt),
+		ClaimedAt:     exportTime(ticket.ClaimedAt),
+		ClaimedBy:     ticket.ClaimedBy,
+		CompletedAt:   exportTime(ticket.CompletedAt),
+		Anonymized:    ticket.Anonymize,
+	}
+
+	if ticket.Anonymize {
+		mac := hmac.New(sha256.New, []byte(hashKey))
+		mac.Write([]byte(ticket.User.UserID))
+		row.UserID = hex.EncodeToString(mac.Sum(nil))
+	} else {
+		row.UserID = ticket.User.UserID
+		row.Email = ticket.User.Email
+		row.DisplayName = ticket.User.DisplayName
+		row.Pronouns = ticket.User.Pronouns
+	}
+	return row
+}
+
+// exportTime formats a time for an export, leaving unset times empty.
+func exportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// writeTicketExport writes the rows of queue's tickets and flushes them to the client.
+func writeTicketExport(writer ticketExportWriter, queue *models.Queue, tickets []*models.Ticket, hashKey string) error {
+	for _, ticket := range tickets {
+		if err := writer.WriteRow(newTicketExportRow(queue, ticket, hashKey)); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
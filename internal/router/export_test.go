@@ -0,0 +1,75 @@
+package router
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"signmeup/internal/models"
+)
+
+// formulaTicket is a ticket whose student-written fields would run as formulas if a spreadsheet read them raw.
+var formulaTicket = &models.Ticket{
+	ID:          "ticket",
+	Status:      models.StatusWaiting,
+	Category:    "+1",
+	Description: `=HYPERLINK("http://example.com","help")`,
+	User:        models.TicketUserdata{UserID: "student", DisplayName: "@student", Email: "student@brown.edu"},
+}
+
+func TestCSVExportEscapesFormulas(t *testing.T) {
+	w := httptest.NewRecorder()
+	writer, err := newTicketExportWriter(w, models.ExportCSV, "queue")
+	if err != nil {
+		t.Fatalf("creating writer: %v", err)
+	}
+	if err := writeTicketExport(writer, &models.Queue{ID: "queue", Title: "-Hours"}, []*models.Ticket{formulaTicket}, ""); err != nil {
+		t.Fatalf("writing export: %v", err)
+	}
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("reading export: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want a header and a row", len(records))
+	}
+	got := make(map[string]string)
+	for i, field := range exportHeader {
+		got[field] = records[1][i]
+	}
+
+	want := map[string]string{
+		"queueTitle":  "'-Hours",
+		"category":    "'+1",
+		"description": `'=HYPERLINK("http://example.com","help")`,
+		"displayName": "'@student",
+		"email":       "student@brown.edu",
+		"ticketID":    "ticket",
+	}
+	for field, value := range want {
+		if got[field] != value {
+			t.Errorf("got %s %q, want %q", field, got[field], value)
+		}
+	}
+}
+
+func TestNDJSONExportKeepsFormulas(t *testing.T) {
+	w := httptest.NewRecorder()
+	writer, err := newTicketExportWriter(w, models.ExportNDJSON, "queue")
+	if err != nil {
+		t.Fatalf("creating writer: %v", err)
+	}
+	if err := writeTicketExport(writer, &models.Queue{ID: "queue"}, []*models.Ticket{formulaTicket}, ""); err != nil {
+		t.Fatalf("writing export: %v", err)
+	}
+
+	var row models.TicketExportRow
+	if err := json.NewDecoder(w.Body).Decode(&row); err != nil {
+		t.Fatalf("reading export: %v", err)
+	}
+	if row.Description != formulaTicket.Description {
+		t.Errorf("got description %q, want %q", row.Description, formulaTicket.Description)
+	}
+}
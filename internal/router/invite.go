@@ -0,0 +1,87 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"signmeup/internal/auth"
+	"signmeup/internal/models"
+	"signmeup/internal/qerrors"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// GET: /{courseID}/invites
+func (h *courseHandler) getCourseInvitesHandler(w http.ResponseWriter, r *http.Request) {
+	invites, err := h.repo.GetCourseInvites(r.Context().Value("courseID").(string))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, invites)
+}
+
+// POST: /{courseID}/invites/{inviteID}/edit
+func (h *courseHandler) editInviteHandler(w http.ResponseWriter, r *http.Request) {
+	var req *models.EditInviteRequest
+
+	user, err := auth.GetUserFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.CourseID = r.Context().Value("courseID").(string)
+	req.InviteID = chi.URLParam(r, "inviteID")
+	req.Actor = user
+
+	err = h.repo.EditInvite(req)
+	if err != nil {
+		switch err {
+		case qerrors.InvalidPermissionError:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case qerrors.InviteNotFoundError:
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write([]byte("Successfully edited invite " + req.InviteID))
+}
+
+// DELETE: /{courseID}/invites/{inviteID}
+func (h *courseHandler) revokeInviteHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetUserFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	req := &models.RevokeInviteRequest{
+		CourseID: r.Context().Value("courseID").(string),
+		InviteID: chi.URLParam(r, "inviteID"),
+		Actor:    user,
+	}
+
+	err = h.repo.RevokeInvite(req)
+	if err != nil {
+		if err == qerrors.InviteNotFoundError {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write([]byte("Successfully revoked invite " + req.InviteID))
+}
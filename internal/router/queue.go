@@ -2,84 +2,159 @@ package router
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/golang/glog"
+	"io"
 	"log"
 	"net/http"
 	"signmeup/internal/auth"
+	"signmeup/internal/config"
 	"signmeup/internal/middleware"
 	"signmeup/internal/models"
-	repo "signmeup/internal/repository"
+	"signmeup/internal/qerrors"
+	"signmeup/internal/repository"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 )
 
-func QueueRoutes() *chi.Mux {
+type queueHandler struct {
+	cfg  *config.ServerConfig
+	repo repository.Repository
+}
+
+func QueueRoutes(cfg *config.ServerConfig, repo repository.Repository, authn *auth.Authenticator) *chi.Mux {
+	h := &queueHandler{cfg: cfg, repo: repo}
 	router := chi.NewRouter()
-	router.Use(auth.AuthCtx())
+	router.Use(authn.AuthCtx())
 
 	// Queue creation
 	// We can't do /{courseID}/create since that will conflate with the ^/{queueID} routes
-	router.With(middleware.CourseCtx(), auth.RequireStaffForCourse()).Post("/create/{courseID}", createQueueHandler)
+	router.With(middleware.CourseCtx(), auth.RequireStaffForCourse()).Post("/create/{courseID}", h.createQueueHandler)
 
 	router.Route("/{queueID}", func(router chi.Router) {
 		// Sets "queueID" from URL param in the context
 		router.Use(middleware.QueueCtx())
 
+		// Live queue updates
+		router.With(authn.RequireQueueVisibility()).Get("/stream", h.streamQueueHandler)
+		router.With(authn.RequireQueueStaff()).Get("/ws", h.queueSocketHandler)
+
 		// Queue modification
-		router.With(auth.RequireQueueStaff()).Post("/edit", editQueueHandler)
-		router.With(auth.RequireQueueStaff()).Patch("/cutoff", cutoffQueueHandler)
-		router.With(auth.RequireQueueStaff()).Patch("/shuffle", shuffleQueueHandler)
-		router.With(auth.RequireQueueStaff(), auth.RequireAdmin()).Delete("/", deleteQueueHandler)
+		router.With(authn.RequireQueueStaff()).Post("/edit", h.editQueueHandler)
+		router.With(authn.RequireQueueStaff()).Patch("/cutoff", h.cutoffQueueHandler)
+		router.With(authn.RequireQueueStaff()).Patch("/shuffle", h.shuffleQueueHandler)
+		router.With(authn.RequireQueueStaff()).Get("/shuffles", h.getShuffleRecordsHandler)
+		router.With(authn.RequireQueueStaff(), auth.RequireAdmin()).Delete("/", h.deleteQueueHandler)
+
+		// Wait estimates
+		router.With(authn.RequireQueueVisibility()).Get("/estimate", h.getWaitEstimateHandler)
+
+		// Ticket triage
+		router.With(authn.RequireQueueStaff()).Get("/tickets", h.getPendingTicketsHandler)
+		router.With(authn.RequireQueueStaff()).Post("/ticket/claimNext", h.claimNextTicketHandler)
+		router.With(authn.RequireQueueStaff()).Get("/tickets/{ticketID}/history", h.getTicketHistoryHandler)
+
+		// Data export
+		router.With(authn.RequireQueueStaff()).Get("/export", h.exportQueueHandler)
 
 		// Ticket modification
-		router.Post("/ticket", createTicketHandler)
-		router.Patch("/ticket", editTicketHandler)
-		router.Post("/ticket/delete", deleteTicketHandler)
+		router.Post("/ticket", h.createTicketHandler)
+		router.With(authn.RequireTicketAccess(true)).Patch("/ticket", h.editTicketHandler)
+		router.With(authn.RequireTicketAccess(false)).Post("/ticket/delete", h.deleteTicketHandler)
 
 		// Announcement
-		router.With(auth.RequireQueueStaff()).Post("/announce", announceHandler)
+		router.With(authn.RequireQueueStaff()).Post("/announce", h.announceHandler)
 	})
 
 	return router
 }
 
-// POST: /{courseID}/create
-func createQueueHandler(w http.ResponseWriter, r *http.Request) {
+// POST: /create/{courseID}
+//
+// If the request has a templateID, the template's settings are used for any fields not given in the request.
+func (h *queueHandler) createQueueHandler(w http.ResponseWriter, r *http.Request) {
 	var req models.CreateQueueRequest
+	courseID := r.Context().Value("courseID").(string)
 
-	err := json.NewDecoder(r.Body).Decode(&req)
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
-		glog.Errorf("Bad request: %v\n", err)
 		return
 	}
-	req.CourseID = r.Context().Value("courseID").(string)
 
-	queue, err := repo.Repository.CreateQueue(&req)
+	err = json.Unmarshal(body, &req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		glog.Errorf("Bad request: %v\n", err)
 		return
 	}
 
+	if req.TemplateID != "" {
+		template, err := h.repo.GetQueueTemplate(courseID, req.TemplateID)
+		if err != nil {
+			if err == qerrors.QueueTemplateNotFoundError {
+				http.Error(w, err.Error(), http.StatusNotFound)
+			} else {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		// Decode the request over the template's settings, so that only the fields given in the request override them.
+		req = template.CreateQueueRequest()
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	req.CourseID = courseID
+
+	queue, err := h.repo.CreateQueue(&req)
+	if err == qerrors.CourseArchivedError {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		glog.Errorf("Bad request: %v\n", err)
+		return
+	}
+
 	render.JSON(w, r, queue)
 }
 
 // PATCH: /shuffle
-func shuffleQueueHandler(w http.ResponseWriter, r *http.Request) {
-	req := &models.ShuffleQueueRequest{QueueID: r.Context().Value("queueID").(string)}
-	err := repo.Repository.ShuffleQueue(req)
+func (h *queueHandler) shuffleQueueHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetUserFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	req := &models.ShuffleQueueRequest{QueueID: r.Context().Value("queueID").(string), ShuffledBy: user}
+	record, err := h.repo.ShuffleQueue(req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	render.JSON(w, r, record)
+}
+
+// GET: /shuffles
+func (h *queueHandler) getShuffleRecordsHandler(w http.ResponseWriter, r *http.Request) {
+	records, err := h.repo.GetShuffleRecords(r.Context().Value("queueID").(string))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, records)
 }
 
 // POST: /edit
-func editQueueHandler(w http.ResponseWriter, r *http.Request) {
+func (h *queueHandler) editQueueHandler(w http.ResponseWriter, r *http.Request) {
 	var req models.EditQueueRequest
 
 	err := json.NewDecoder(r.Body).Decode(&req)
@@ -90,7 +165,7 @@ func editQueueHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	req.QueueID = r.Context().Value("queueID").(string)
 
-	err = repo.Repository.EditQueue(&req)
+	err = h.repo.EditQueue(&req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		glog.Errorf("Bad request: %v\n", err)
@@ -102,7 +177,7 @@ func editQueueHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // POST: /cutoff/{queueID}
-func cutoffQueueHandler(w http.ResponseWriter, r *http.Request) {
+func (h *queueHandler) cutoffQueueHandler(w http.ResponseWriter, r *http.Request) {
 	var req models.CutoffQueueRequest
 
 	err := json.NewDecoder(r.Body).Decode(&req)
@@ -112,7 +187,7 @@ func cutoffQueueHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	req.QueueID = chi.URLParam(r, "queueID")
-	err = repo.Repository.CutoffQueue(&req)
+	err = h.repo.CutoffQueue(&req)
 	if err != nil {
 		if err.Error() == "queue not found" {
 			http.Error(w, err.Error(), http.StatusBadRequest)
@@ -127,11 +202,11 @@ func cutoffQueueHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // POST: /delete
-func deleteQueueHandler(w http.ResponseWriter, r *http.Request) {
+func (h *queueHandler) deleteQueueHandler(w http.ResponseWriter, r *http.Request) {
 	var req models.DeleteQueueRequest
 
 	req.QueueID = r.Context().Value("queueID").(string)
-	err := repo.Repository.DeleteQueue(&req)
+	err := h.repo.DeleteQueue(&req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -142,7 +217,7 @@ func deleteQueueHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // POST: /ticket/create/{queueID}
-func createTicketHandler(w http.ResponseWriter, r *http.Request) {
+func (h *queueHandler) createTicketHandler(w http.ResponseWriter, r *http.Request) {
 	var req models.CreateTicketRequest
 
 	user, err := auth.GetUserFromRequest(r)
@@ -159,17 +234,152 @@ func createTicketHandler(w http.ResponseWriter, r *http.Request) {
 	req.QueueID = chi.URLParam(r, "queueID")
 	req.CreatedBy = user
 
-	ticket, err := repo.Repository.CreateTicket(&req)
+	ticket, err := h.repo.CreateTicket(&req)
+	if err != nil {
+		switch err {
+		case qerrors.MissingCategoryError, qerrors.InvalidCategoryError:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case qerrors.QueueClosedError, qerrors.NotEnrolledError, qerrors.CourseArchivedError:
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// The ticket has been created, so a failure to estimate its wait is not an error.
+	res := &models.CreateTicketResponse{Ticket: ticket}
+	estimate, err := h.repo.GetWaitEstimate(req.QueueID)
+	if err != nil {
+		glog.Warningf("error estimating wait for ticket %v: %v\n", ticket.ID, err)
+	} else {
+		for _, e := range estimate.Tickets {
+			if e.TicketID == ticket.ID {
+				res.EstimatedWaitSeconds = e.EstimatedWaitSeconds
+			}
+		}
+	}
+
+	render.JSON(w, r, res)
+}
+
+// GET: /{queueID}/estimate
+func (h *queueHandler) getWaitEstimateHandler(w http.ResponseWriter, r *http.Request) {
+	estimate, err := h.repo.GetWaitEstimate(r.Context().Value("queueID").(string))
+	if err != nil {
+		if err == qerrors.QueueNotFoundError {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	render.JSON(w, r, estimate)
+}
+
+// GET: /{queueID}/export?format=
+func (h *queueHandler) exportQueueHandler(w http.ResponseWriter, r *http.Request) {
+	queueID := r.Context().Value("queueID").(string)
+
+	queue, err := h.repo.GetQueue(queueID)
+	if err != nil {
+		if err == qerrors.QueueNotFoundError {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	tickets, err := h.repo.GetTickets(queueID, time.Time{}, time.Time{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writer, err := newTicketExportWriter(w, models.ExportFormat(r.URL.Query().Get("format")), "queue-"+queueID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := writeTicketExport(writer, queue, tickets, h.cfg.ExportHashKey); err != nil {
+		glog.Warningf("error exporting queue %v: %v\n", queueID, err)
+	}
+}
+
+// GET: /{queueID}/tickets?category=
+func (h *queueHandler) getPendingTicketsHandler(w http.ResponseWriter, r *http.Request) {
+	tickets, err := h.repo.GetPendingTickets(r.Context().Value("queueID").(string))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	// Filter by category, if one was given.
+	if category := r.URL.Query().Get("category"); category != "" {
+		filtered := make([]*models.Ticket, 0, len(tickets))
+		for _, t := range tickets {
+			if t.Category == category {
+				filtered = append(filtered, t)
+			}
+		}
+		tickets = filtered
+	}
+
+	render.JSON(w, r, tickets)
+}
+
+// GET: /{queueID}/tickets/{ticketID}/history
+func (h *queueHandler) getTicketHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	events, err := h.repo.GetTicketEvents(r.Context().Value("queueID").(string), chi.URLParam(r, "ticketID"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, events)
+}
+
+// POST: /{queueID}/ticket/claimNext
+func (h *queueHandler) claimNextTicketHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.ClaimNextTicketRequest
+
+	user, err := auth.GetUserFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	// The body, and so the category, is optional.
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && err != io.EOF {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req.QueueID = r.Context().Value("queueID").(string)
+	req.ClaimedBy = user
+
+	ticket, err := h.repo.ClaimNextTicket(&req)
+	if err != nil {
+		switch err {
+		case qerrors.NoWaitingTicketsError:
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case qerrors.InvalidCategoryError:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
 	render.JSON(w, r, ticket)
 }
 
 // POST: /ticket/edit/{queueID}
-func editTicketHandler(w http.ResponseWriter, r *http.Request) {
+func (h *queueHandler) editTicketHandler(w http.ResponseWriter, r *http.Request) {
 	var req *models.EditTicketRequest
 
 	user, err := auth.GetUserFromRequest(r)
@@ -186,8 +396,18 @@ func editTicketHandler(w http.ResponseWriter, r *http.Request) {
 	req.QueueID = chi.URLParam(r, "queueID")
 	req.ClaimedBy = user
 
-	err = repo.Repository.EditTicket(req)
+	err = h.repo.EditTicket(req)
 	if err != nil {
+		var transitionErr *qerrors.TicketTransitionError
+		if errors.As(err, &transitionErr) {
+			if transitionErr.Forbidden {
+				http.Error(w, err.Error(), http.StatusForbidden)
+			} else {
+				http.Error(w, err.Error(), http.StatusConflict)
+			}
+			return
+		}
+
 		log.Println(err.Error())
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -198,17 +418,24 @@ func editTicketHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // POST: /ticket/delete/{queueID}
-func deleteTicketHandler(w http.ResponseWriter, r *http.Request) {
+func (h *queueHandler) deleteTicketHandler(w http.ResponseWriter, r *http.Request) {
 	var req *models.DeleteTicketRequest
 
-	err := json.NewDecoder(r.Body).Decode(&req)
+	user, err := auth.GetUserFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 	req.QueueID = r.Context().Value("queueID").(string)
+	req.DeletedBy = user
 
-	err = repo.Repository.DeleteTicket(req)
+	err = h.repo.DeleteTicket(req)
 	if err != nil {
 		log.Println(err.Error())
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -220,7 +447,7 @@ func deleteTicketHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // POST: /{queueID}/announce
-func announceHandler(w http.ResponseWriter, r *http.Request) {
+func (h *queueHandler) announceHandler(w http.ResponseWriter, r *http.Request) {
 	var req models.MakeAnnouncementRequest
 
 	err := json.NewDecoder(r.Body).Decode(&req)
@@ -230,7 +457,7 @@ func announceHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	req.QueueID = r.Context().Value("queueID").(string)
 
-	err = repo.Repository.MakeAnnouncement(&req)
+	err = h.repo.MakeAnnouncement(&req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -0,0 +1,92 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"signmeup/internal/models"
+	"signmeup/internal/qerrors"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// GET: /{courseID}/schedules
+func (h *courseHandler) getSchedulesHandler(w http.ResponseWriter, r *http.Request) {
+	schedules, err := h.repo.GetSchedules(r.Context().Value("courseID").(string))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, schedules)
+}
+
+// POST: /{courseID}/schedules
+func (h *courseHandler) createScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	var req *models.CreateScheduleRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.CourseID = r.Context().Value("courseID").(string)
+
+	schedule, err := h.repo.CreateSchedule(req)
+	if err != nil {
+		if err == qerrors.InvalidScheduleError {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	render.JSON(w, r, schedule)
+}
+
+// POST: /{courseID}/schedules/{scheduleID}/edit
+func (h *courseHandler) editScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	var req *models.EditScheduleRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.CourseID = r.Context().Value("courseID").(string)
+	req.ScheduleID = chi.URLParam(r, "scheduleID")
+
+	err = h.repo.EditSchedule(req)
+	if err != nil {
+		switch err {
+		case qerrors.InvalidScheduleError:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case qerrors.ScheduleNotFoundError:
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write([]byte("Successfully edited schedule " + req.ScheduleID))
+}
+
+// DELETE: /{courseID}/schedules/{scheduleID}
+func (h *courseHandler) deleteScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	req := &models.DeleteScheduleRequest{
+		CourseID:   r.Context().Value("courseID").(string),
+		ScheduleID: chi.URLParam(r, "scheduleID"),
+	}
+
+	err := h.repo.DeleteSchedule(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write([]byte("Successfully deleted schedule " + req.ScheduleID))
+}
@@ -0,0 +1,165 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"signmeup/internal/auth"
+	"signmeup/internal/models"
+	"signmeup/internal/qerrors"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// socketPingInterval is how often a ping is sent to check that the client is still connected.
+	socketPingInterval = 30 * time.Second
+	// socketPongWait is how long to wait for any message, including a pong, before closing the connection.
+	socketPongWait = 2 * socketPingInterval
+	// socketWriteWait is how long a single write may take.
+	socketWriteWait = 10 * time.Second
+)
+
+// commandStatuses maps each TicketCommandType to the status it moves a ticket to.
+var commandStatuses = map[models.TicketCommandType]models.TicketStatus{
+	models.CommandClaim:    models.StatusClaimed,
+	models.CommandReturn:   models.StatusReturned,
+	models.CommandMissing:  models.StatusMissing,
+	models.CommandComplete: models.StatusComplete,
+}
+
+// GET: /{queueID}/ws
+//
+// queueSocketHandler upgrades the request to a WebSocket over which staff receive the same updates as
+// streamQueueHandler, and can send TicketCommands. Each command is answered with an "ack" or "error" message carrying
+// the command's ID.
+func (h *queueHandler) queueSocketHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetUserFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	queueID := r.Context().Value("queueID").(string)
+	snapshots, err := h.repo.WatchQueue(r.Context(), queueID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	upgrader := websocket.Upgrader{CheckOrigin: h.checkOrigin}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already replied to the client.
+		glog.Warningf("error upgrading queue socket: %v\n", err)
+		return
+	}
+	defer conn.Close()
+
+	// Commands are read and executed on a separate goroutine, and their replies are written below, since a connection
+	// supports only one concurrent writer.
+	replies := make(chan models.QueueSocketMessage)
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+
+		_ = conn.SetReadDeadline(time.Now().Add(socketPongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(socketPongWait))
+		})
+
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			_ = conn.SetReadDeadline(time.Now().Add(socketPongWait))
+
+			var cmd models.TicketCommand
+			reply := models.QueueSocketMessage{Type: "ack"}
+			if err := json.Unmarshal(data, &cmd); err != nil {
+				reply = models.QueueSocketMessage{Type: "error", Error: qerrors.InvalidBody.Error()}
+			} else if err := h.runTicketCommand(queueID, user, &cmd); err != nil {
+				reply = models.QueueSocketMessage{Type: "error", ID: cmd.ID, Error: err.Error()}
+			} else {
+				reply.ID = cmd.ID
+			}
+
+			select {
+			case replies <- reply:
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}()
+
+	write := func(msg models.QueueSocketMessage) error {
+		_ = conn.SetWriteDeadline(time.Now().Add(socketWriteWait))
+		return conn.WriteJSON(msg)
+	}
+	emit := func(event string, data []byte) error {
+		return write(models.QueueSocketMessage{Type: event, Data: data})
+	}
+
+	stream := &queueStream{viewer: user}
+	ping := time.NewTicker(socketPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case snapshot, ok := <-snapshots:
+			if !ok {
+				// The queue was deleted.
+				_ = conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseNormalClosure, "queue deleted"),
+					time.Now().Add(socketWriteWait))
+				return
+			}
+			err = stream.write(snapshot, emit)
+		case reply := <-replies:
+			err = write(reply)
+		case <-ping.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(socketWriteWait))
+		case <-closed:
+			return
+		}
+
+		if err != nil {
+			glog.Warningf("error writing to queue socket: %v\n", err)
+			return
+		}
+	}
+}
+
+// runTicketCommand applies a TicketCommand sent by the given user through EditTicket. Commands only change a ticket's
+// status, so its description is kept as it is when the edit is made.
+func (h *queueHandler) runTicketCommand(queueID string, user *models.User, cmd *models.TicketCommand) error {
+	status, ok := commandStatuses[cmd.Command]
+	if !ok {
+		return qerrors.InvalidCommandError
+	}
+
+	return h.repo.EditTicket(&models.EditTicketRequest{
+		ID:              cmd.TicketID,
+		QueueID:         queueID,
+		Status:          status,
+		KeepDescription: true,
+		ClaimedBy:       user,
+	})
+}
+
+// checkOrigin only accepts WebSocket connections from the origins the server allows requests from.
+func (h *queueHandler) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	for _, allowed := range h.cfg.AllowedOrigins {
+		if origin == allowed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"signmeup/internal/auth"
+	"signmeup/internal/models"
+	"signmeup/internal/qerrors"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// streamHeartbeatInterval is how often a comment is sent on an idle stream to keep the connection open.
+const streamHeartbeatInterval = 30 * time.Second
+
+// GET: /{queueID}/stream
+//
+// streamQueueHandler streams a queue over Server-Sent Events. A "queue" event is sent with the queue document and a
+// "tickets" event with the queue's pending tickets, in order, whenever either changes. A "ticket" event is sent with a
+// models.TicketStatusChange whenever a ticket is created or changes status.
+func (h *queueHandler) streamQueueHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetUserFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	queueID := r.Context().Value("queueID").(string)
+	snapshots, err := h.repo.WatchQueue(r.Context(), queueID)
+	if err != nil {
+		if err == qerrors.QueueNotFoundError {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	stream := &queueStream{viewer: user}
+	emit := func(event string, data []byte) error {
+		return writeEvent(w, event, data)
+	}
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case snapshot, ok := <-snapshots:
+			if !ok {
+				// The queue was deleted or the client went away.
+				return
+			}
+
+			if err := stream.write(snapshot, emit); err != nil {
+				glog.Warningf("error writing to queue stream: %v\n", err)
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// queueStream tracks what has already been sent to a single viewer of a queue, so that only changes are streamed.
+type queueStream struct {
+	viewer *models.User
+
+	lastQueue   []byte
+	lastTickets []byte
+	statuses    map[string]models.TicketStatus
+}
+
+// write emits the events describing how snapshot differs from the previously written snapshot.
+func (s *queueStream) write(snapshot *models.QueueSnapshot, emit func(event string, data []byte) error) error {
+	isStaff := s.viewer.HasStaffPermission(snapshot.Queue.CourseID)
+
+	queue, err := json.Marshal(snapshot.Queue)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(queue, s.lastQueue) {
+		if err := emit("queue", queue); err != nil {
+			return err
+		}
+		s.lastQueue = queue
+	}
+
+	pending := make([]*models.Ticket, 0, len(snapshot.Queue.PendingTickets))
+	for _, id := range snapshot.Queue.PendingTickets {
+		if ticket, ok := snapshot.Tickets[id]; ok {
+			pending = append(pending, redactTicket(ticket, s.viewer, isStaff))
+		}
+	}
+	tickets, err := json.Marshal(pending)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(tickets, s.lastTickets) {
+		if err := emit("tickets", tickets); err != nil {
+			return err
+		}
+		s.lastTickets = tickets
+	}
+
+	// The first snapshot establishes the statuses that later changes are compared against.
+	first := s.statuses == nil
+	statuses := make(map[string]models.TicketStatus, len(snapshot.Tickets))
+	for id, ticket := range snapshot.Tickets {
+		statuses[id] = ticket.Status
+		if first || s.statuses[id] == ticket.Status {
+			continue
+		}
+
+		change, err := json.Marshal(models.TicketStatusChange{TicketID: id, From: s.statuses[id], To: ticket.Status})
+		if err != nil {
+			return err
+		}
+		if err := emit("ticket", change); err != nil {
+			return err
+		}
+	}
+	s.statuses = statuses
+
+	return nil
+}
+
+// writeEvent writes a single Server-Sent Event.
+func writeEvent(w http.ResponseWriter, event string, data []byte) error {
+	_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
+
+// redactTicket hides the identity of the owner of an anonymized ticket from anyone other than the owner and course
+// staff.
+func redactTicket(ticket *models.Ticket, viewer *models.User, isStaff bool) *models.Ticket {
+	if !ticket.Anonymize || isStaff || ticket.User.UserID == viewer.ID {
+		return ticket
+	}
+
+	redacted := *ticket
+	redacted.User = models.TicketUserdata{}
+	return &redacted
+}
@@ -0,0 +1,93 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"signmeup/internal/models"
+	"signmeup/internal/qerrors"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// GET: /{courseID}/templates
+func (h *courseHandler) getQueueTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	templates, err := h.repo.GetQueueTemplates(r.Context().Value("courseID").(string))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, templates)
+}
+
+// POST: /{courseID}/templates
+func (h *courseHandler) createQueueTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	var req *models.CreateQueueTemplateRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.CourseID = r.Context().Value("courseID").(string)
+
+	template, err := h.repo.CreateQueueTemplate(req)
+	if err != nil {
+		switch err {
+		case qerrors.InvalidQueueTemplateError, qerrors.InvalidOrderingPolicyError, qerrors.InvalidCategoriesError:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	render.JSON(w, r, template)
+}
+
+// POST: /{courseID}/templates/{templateID}/edit
+func (h *courseHandler) editQueueTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	var req *models.EditQueueTemplateRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.CourseID = r.Context().Value("courseID").(string)
+	req.TemplateID = chi.URLParam(r, "templateID")
+
+	err = h.repo.EditQueueTemplate(req)
+	if err != nil {
+		switch err {
+		case qerrors.InvalidQueueTemplateError, qerrors.InvalidOrderingPolicyError, qerrors.InvalidCategoriesError:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case qerrors.QueueTemplateNotFoundError:
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write([]byte("Successfully edited queue template " + req.TemplateID))
+}
+
+// DELETE: /{courseID}/templates/{templateID}
+func (h *courseHandler) deleteQueueTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	req := &models.DeleteQueueTemplateRequest{
+		CourseID:   r.Context().Value("courseID").(string),
+		TemplateID: chi.URLParam(r, "templateID"),
+	}
+
+	err := h.repo.DeleteQueueTemplate(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write([]byte("Successfully deleted queue template " + req.TemplateID))
+}
@@ -0,0 +1,159 @@
+package scheduler
+
+import (
+	"context"
+	"signmeup/internal/config"
+	"signmeup/internal/models"
+	"signmeup/internal/qerrors"
+	"signmeup/internal/repository"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// Scheduler periodically runs background jobs against the repository. Every job must be safe to run on several
+// instances of the server at once.
+type Scheduler struct {
+	cfg  *config.ServerConfig
+	repo repository.Repository
+}
+
+func New(cfg *config.ServerConfig, repo repository.Repository) *Scheduler {
+	return &Scheduler{cfg: cfg, repo: repo}
+}
+
+// Start runs the scheduler's jobs every cfg.SchedulerInterval, in a new goroutine, until ctx is done.
+func (s *Scheduler) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.cfg.SchedulerInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case now := <-ticker.C:
+				s.startScheduledSessions(now)
+				s.closeEndedQueues(now)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// startScheduledSessions creates a queue for every scheduled session that has started but does not have one yet. The
+// queue's end time is the session's, so it is closed by closeEndedQueues.
+func (s *Scheduler) startScheduledSessions(now time.Time) {
+	schedules, err := s.repo.GetAllSchedules()
+	if err != nil {
+		glog.Warningf("error getting schedules: %v\n", err)
+		return
+	}
+
+	// Archived courses keep their schedules, but hold no more sessions. Their sessions are never marked as started, so
+	// they are skipped before trying to start them, rather than failing to start on every run.
+	archived := make(map[string]bool)
+	for _, schedule := range schedules {
+		date, _, _, ok := schedule.Occurrence(now)
+		if !ok || schedule.LastOccurrence == date {
+			continue
+		}
+		if s.courseArchived(schedule.CourseID, archived) {
+			continue
+		}
+
+		queue, err := s.repo.StartScheduledSession(schedule.CourseID, schedule.ID, now)
+		if err == qerrors.SessionAlreadyStartedError {
+			// Another instance started the session first.
+			continue
+		} else if err == qerrors.CourseArchivedError {
+			// The course was archived since it was checked.
+			continue
+		} else if err != nil {
+			glog.Warningf("error starting session of schedule %v: %v\n", schedule.ID, err)
+			continue
+		}
+
+		s.notifyStaff(schedule, queue)
+	}
+}
+
+// courseArchived returns true if a course is archived, remembering the answer in archived so that each course is only
+// read once per run.
+func (s *Scheduler) courseArchived(courseID string, archived map[string]bool) bool {
+	if isArchived, ok := archived[courseID]; ok {
+		return isArchived
+	}
+
+	course, err := s.repo.GetCourseByID(courseID)
+	if err != nil {
+		// Let StartScheduledSession report the problem.
+		return false
+	}
+	archived[courseID] = course.IsArchived
+	return course.IsArchived
+}
+
+// notifyStaff tells the staff assigned to a scheduled session that its queue has opened.
+func (s *Scheduler) notifyStaff(schedule *models.Schedule, queue *models.Queue) {
+	for _, userID := range schedule.Staff {
+		notification := models.Notification{
+			Title:     "Your office hours queue has opened",
+			Body:      queue.Course.Code,
+			Timestamp: time.Now(),
+			Type:      models.NotificationAnnouncement,
+		}
+		if err := s.repo.AddNotification(userID, notification); err != nil {
+			glog.Warningf("error sending queue opened notification: %v\n", err)
+		}
+	}
+}
+
+// closeEndedQueues cuts off every queue whose end time has passed. The cutoff is conditional on the queue still being
+// open, so only the instance that actually closes a queue notifies its students.
+func (s *Scheduler) closeEndedQueues(now time.Time) {
+	queues, err := s.repo.GetEndedQueues(now)
+	if err != nil {
+		glog.Warningf("error getting ended queues: %v\n", err)
+		return
+	}
+
+	for _, queue := range queues {
+		err := s.repo.CutoffQueue(&models.CutoffQueueRequest{QueueID: queue.ID, IsCutOff: true, EndedBy: now})
+		if err == qerrors.QueueNotEndedError {
+			// Another instance closed the queue first, or it was edited.
+			continue
+		} else if err != nil {
+			glog.Warningf("error closing queue %v: %v\n", queue.ID, err)
+			continue
+		}
+
+		if s.cfg.NotifyOnAutoClose {
+			s.notifyWaiting(queue)
+		}
+	}
+}
+
+// notifyWaiting tells the students still waiting in a queue that it has closed.
+func (s *Scheduler) notifyWaiting(queue *models.Queue) {
+	tickets, err := s.repo.GetPendingTickets(queue.ID)
+	if err != nil {
+		glog.Warningf("error getting tickets of closed queue %v: %v\n", queue.ID, err)
+		return
+	}
+
+	for _, ticket := range tickets {
+		if ticket.Status != models.StatusWaiting && ticket.Status != models.StatusReturned {
+			continue
+		}
+
+		notification := models.Notification{
+			Title:     "The queue has closed",
+			Body:      queue.Course.Code,
+			Timestamp: time.Now(),
+			Type:      models.NotificationAnnouncement,
+		}
+		if err := s.repo.AddNotification(ticket.User.UserID, notification); err != nil {
+			glog.Warningf("error sending queue closed notification: %v\n", err)
+		}
+	}
+}
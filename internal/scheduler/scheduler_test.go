@@ -0,0 +1,60 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"signmeup/internal/config"
+	"signmeup/internal/models"
+	"signmeup/internal/repository"
+)
+
+// countingRepository is a memory repository that counts the scheduled sessions it is asked to start.
+type countingRepository struct {
+	*repository.MemoryRepository
+	starts int
+}
+
+func (cr *countingRepository) StartScheduledSession(courseID string, scheduleID string, now time.Time) (*models.Queue, error) {
+	cr.starts++
+	return cr.MemoryRepository.StartScheduledSession(courseID, scheduleID, now)
+}
+
+func TestStartScheduledSessionsSkipsArchivedCourses(t *testing.T) {
+	repo := &countingRepository{MemoryRepository: repository.NewMemoryRepository()}
+	course, err := repo.CreateCourse(&models.CreateCourseRequest{Title: "Intro", Code: "cs0150", Term: "fall"})
+	if err != nil {
+		t.Fatalf("creating course: %v", err)
+	}
+	_, err = repo.CreateSchedule(&models.CreateScheduleRequest{
+		CourseID:  course.ID,
+		Title:     "Hours",
+		Weekday:   time.Monday,
+		StartTime: "10:00",
+		EndTime:   "12:00",
+		TimeZone:  "America/New_York",
+	})
+	if err != nil {
+		t.Fatalf("creating schedule: %v", err)
+	}
+	if err := repo.SetCourseArchived(&models.SetCourseArchivedRequest{CourseID: course.ID, Archived: true}); err != nil {
+		t.Fatalf("archiving course: %v", err)
+	}
+
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("loading time zone: %v", err)
+	}
+	s := New(config.DefaultDevelopmentConfig(), repo)
+	// Every run during the session skips it.
+	for now := time.Date(2026, time.October, 12, 10, 0, 0, 0, loc); now.Hour() < 12; now = now.Add(30 * time.Minute) {
+		s.startScheduledSessions(now)
+	}
+
+	if repo.starts != 0 {
+		t.Errorf("tried to start %d sessions of an archived course, want 0", repo.starts)
+	}
+	if queues, err := repo.GetEndedQueues(time.Date(2026, time.October, 13, 0, 0, 0, 0, loc)); err != nil || len(queues) != 0 {
+		t.Errorf("got queues %v and error %v, want no queues", queues, err)
+	}
+}
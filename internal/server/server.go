@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"signmeup/internal/auth"
 	"signmeup/internal/config"
+	"signmeup/internal/repository"
 	rtr "signmeup/internal/router"
 
 	"github.com/go-chi/chi/v5"
@@ -12,7 +14,24 @@ import (
 	"github.com/rs/cors"
 )
 
-func Routes() *chi.Mux {
+// New creates the server's HTTP handler. All state is provided by the arguments, so several differently configured
+// servers can coexist in the same process.
+func New(cfg *config.ServerConfig, repo repository.Repository, verifier auth.Verifier) http.Handler {
+	router := Routes(cfg, repo, verifier)
+	c := cors.New(cors.Options{
+		AllowedOrigins:   cfg.AllowedOrigins,
+		AllowedHeaders:   []string{"Cookie", "Content-Type"},
+		AllowedMethods:   []string{"GET", "POST", "DELETE", "PATCH"},
+		ExposedHeaders:   []string{"Set-Cookie"},
+		AllowCredentials: true,
+	})
+
+	return c.Handler(router)
+}
+
+func Routes(cfg *config.ServerConfig, repo repository.Repository, verifier auth.Verifier) *chi.Mux {
+	authn := auth.NewAuthenticator(cfg, verifier, repo)
+
 	router := chi.NewRouter()
 	router.Use(
 		middleware.Logger, // Log API Request Calls
@@ -23,29 +42,21 @@ func Routes() *chi.Mux {
 	})
 
 	router.Route("/v1", func(r chi.Router) {
-		r.Mount("/users", rtr.AuthRoutes())
-		r.Mount("/courses", rtr.CourseRoutes())
-		r.Mount("/queues", rtr.QueueRoutes())
+		r.Mount("/users", rtr.AuthRoutes(cfg, repo, verifier, authn))
+		r.Mount("/courses", rtr.CourseRoutes(cfg, repo, authn))
+		r.Mount("/queues", rtr.QueueRoutes(cfg, repo, authn))
+		r.Mount("/audit", rtr.AuditRoutes(repo, authn))
 	})
 
 	return router
 }
 
-func Start() {
-	if config.Config == nil {
+// Start listens for requests on the configured port and serves them with handler.
+func Start(cfg *config.ServerConfig, handler http.Handler) {
+	if cfg == nil {
 		log.Panic("❌ Missing or invalid configuration!")
 	}
 
-	router := Routes()
-	c := cors.New(cors.Options{
-		AllowedOrigins:   config.Config.AllowedOrigins,
-		AllowedHeaders:   []string{"Cookie", "Content-Type"},
-		AllowedMethods:   []string{"GET", "POST", "DELETE", "PATCH"},
-		ExposedHeaders:   []string{"Set-Cookie"},
-		AllowCredentials: true,
-	})
-
-	handler := c.Handler(router)
-	log.Printf("Server is listening on port %v\n", config.Config.Port)
-	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%v", config.Config.Port), handler))
+	log.Printf("Server is listening on port %v\n", cfg.Port)
+	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%v", cfg.Port), handler))
 }
@@ -1,9 +1,38 @@
 package main
 
 import (
+	"log"
+	// Embed the time zone database, which schedules are defined in terms of.
+	_ "time/tzdata"
+
+	"signmeup/internal/auth"
+	"signmeup/internal/config"
+	"signmeup/internal/firebase"
+	"signmeup/internal/repository"
+	"signmeup/internal/scheduler"
 	"signmeup/internal/server"
 )
 
 func main() {
-	server.Start()
+	cfg := config.FromEnvironment()
+
+	app, err := firebase.NewApp(cfg.FirebaseConfig)
+	if err != nil {
+		log.Panicf("Error initializing Firebase app: %v\n", err)
+	}
+
+	repo, err := repository.NewFirebaseRepository(cfg, app)
+	if err != nil {
+		log.Panicf("Error creating repository: %v\n", err)
+	}
+	log.Printf("✅ Successfully created Firebase repository client")
+
+	authClient, err := app.Auth(firebase.Context)
+	if err != nil {
+		log.Panicf("Error creating auth client: %v\n", err)
+	}
+
+	scheduler.New(cfg, repo).Start(firebase.Context)
+
+	server.Start(cfg, server.New(cfg, repo, auth.NewFirebaseVerifier(authClient)))
 }